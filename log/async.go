@@ -0,0 +1,220 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// AsyncOption is a function that applies a configuration option to an
+	// asynchronous output.
+	AsyncOption func(*asyncOptions)
+
+	// AsyncPolicy determines what happens when an entry is written to an
+	// asynchronous output whose queue is full.
+	AsyncPolicy int
+
+	// AsyncWriter writes log entries to an output from a background
+	// goroutine. Use Output to retrieve the output given to WithOutputs and
+	// Close to drain the queue on shutdown.
+	AsyncWriter struct {
+		out     Output
+		policy  AsyncPolicy
+		queue   chan *Entry
+		lock    sync.RWMutex
+		closed  bool
+		done    chan struct{}
+		dropped atomic.Uint64
+		onError func(*Entry, error)
+	}
+
+	asyncOptions struct {
+		size    int
+		policy  AsyncPolicy
+		onError func(*Entry, error)
+	}
+)
+
+const (
+	// AsyncBlock blocks the logging goroutine until there is room in the
+	// queue.
+	AsyncBlock AsyncPolicy = iota + 1
+	// AsyncDropNewest drops the entry being written.
+	AsyncDropNewest
+	// AsyncDropOldest drops the oldest entry in the queue to make room for
+	// the entry being written.
+	AsyncDropOldest
+)
+
+// DefaultAsyncQueueSize is the default number of entries an asynchronous
+// output queues before applying its full queue policy.
+const DefaultAsyncQueueSize = 1024
+
+// ErrAsyncWriterClosed is returned when writing to a closed asynchronous
+// output.
+var ErrAsyncWriterClosed = errors.New("log: async writer closed")
+
+// ErrAsyncQueueFull is reported when an entry is dropped because the queue of
+// an asynchronous output is full.
+var ErrAsyncQueueFull = errors.New("log: async queue full")
+
+// AsyncOutput returns an asynchronous writer that formats and writes the log
+// entries to out from a background goroutine so that slow writers do not block
+// the logging goroutines. Entries are queued in a bounded queue, when the queue
+// is full the policy set with WithAsyncPolicy applies (AsyncDropNewest by
+// default). Entries dropped under AsyncDropNewest or after Close are reported
+// as write errors to the logger, and so are counted by the log.write.errors
+// metric (see WithMeter). Use WithAsyncErrorHandler to be notified of the
+// errors returned by out from the background goroutine and of every dropped
+// entry.
+//
+// Usage:
+//
+//	async := log.AsyncOutput(log.Output{Writer: os.Stdout, Format: log.FormatJSON})
+//	defer async.Close(ctx)
+//	ctx := log.Context(context.Background(), log.WithOutputs(async.Output()))
+func AsyncOutput(out Output, opts ...AsyncOption) *AsyncWriter {
+	o := asyncOptions{size: DefaultAsyncQueueSize, policy: AsyncDropNewest}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.size <= 0 {
+		o.size = 1
+	}
+	w := &AsyncWriter{
+		out:     out,
+		policy:  o.policy,
+		queue:   make(chan *Entry, o.size),
+		done:    make(chan struct{}),
+		onError: o.onError,
+	}
+	go w.run()
+	return w
+}
+
+// WithAsyncQueueSize sets the maximum number of entries queued by an
+// asynchronous output.
+func WithAsyncQueueSize(n int) AsyncOption {
+	return func(o *asyncOptions) {
+		o.size = n
+	}
+}
+
+// WithAsyncPolicy sets the policy applied when the queue of an asynchronous
+// output is full.
+func WithAsyncPolicy(p AsyncPolicy) AsyncOption {
+	return func(o *asyncOptions) {
+		o.policy = p
+	}
+}
+
+// WithAsyncErrorHandler sets a function called with the errors returned by the
+// wrapped output and with the entries dropped by an asynchronous output, in
+// which case the error is ErrAsyncQueueFull or ErrAsyncWriterClosed. The
+// function is called from the background goroutine for write errors and from
+// the logging goroutine for dropped entries, it must be safe for concurrent
+// use and must not block.
+func WithAsyncErrorHandler(fn func(e *Entry, err error)) AsyncOption {
+	return func(o *asyncOptions) {
+		o.onError = fn
+	}
+}
+
+// Output returns the output to use with WithOutputs.
+func (w *AsyncWriter) Output() Output {
+	return Output{Handle: w.handle}
+}
+
+// Dropped returns the number of entries dropped because the queue was full or
+// the writer was closed.
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close stops accepting new entries and waits for the queued entries to be
+// written. Close returns the context error if ctx is done before the queue is
+// drained.
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.lock.Unlock()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handle queues e applying the full queue policy. Entries filtered out by the
+// wrapped output are not queued. handle returns an error if e is dropped.
+func (w *AsyncWriter) handle(e *Entry) error {
+	if !w.out.accepts(e) {
+		return nil
+	}
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		w.drop(e, ErrAsyncWriterClosed)
+		return ErrAsyncWriterClosed
+	}
+	switch w.policy {
+	case AsyncBlock:
+		w.queue <- e
+	case AsyncDropOldest:
+		for {
+			select {
+			case w.queue <- e:
+				return nil
+			default:
+			}
+			select {
+			case old := <-w.queue:
+				w.drop(old, ErrAsyncQueueFull)
+			default:
+			}
+		}
+	default:
+		select {
+		case w.queue <- e:
+		default:
+			w.drop(e, ErrAsyncQueueFull)
+			return ErrAsyncQueueFull
+		}
+	}
+	return nil
+}
+
+// drop records that e was dropped.
+func (w *AsyncWriter) drop(e *Entry, err error) {
+	w.dropped.Add(1)
+	w.report(e, err)
+}
+
+// report calls the error handler if any.
+func (w *AsyncWriter) report(e *Entry, err error) {
+	if w.onError != nil {
+		w.onError(e, err)
+	}
+}
+
+// run writes the queued entries until the queue is closed and drained.
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for e := range w.queue {
+		var err error
+		if w.out.Handle != nil {
+			err = w.out.Handle(e)
+		} else {
+			_, err = w.out.Writer.Write(w.out.Format(e))
+		}
+		if err != nil {
+			w.report(e, err)
+		}
+	}
+}
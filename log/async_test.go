@@ -0,0 +1,200 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// blockingWriter blocks writes until unblocked.
+type blockingWriter struct {
+	Buffer
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{started: make(chan struct{}, 1), unblock: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.unblock
+	return w.Buffer.Write(p)
+}
+
+func TestAsyncOutput(t *testing.T) {
+	var buf Buffer
+	async := AsyncOutput(Output{Writer: &buf, Format: testFormat})
+	ctx := Context(context.Background(), WithOutputs(async.Output()))
+
+	Printf(ctx, "one")
+	Printf(ctx, "two")
+	require.NoError(t, async.Close(context.Background()))
+
+	assert.Equal(t, "onetwo", buf.String())
+	assert.Zero(t, async.Dropped())
+
+	Printf(ctx, "closed")
+	assert.Equal(t, "onetwo", buf.String())
+	assert.Equal(t, uint64(1), async.Dropped())
+	assert.NoError(t, async.Close(context.Background()), "Close must be idempotent")
+}
+
+func TestAsyncOutputPolicies(t *testing.T) {
+	cases := []struct {
+		name    string
+		policy  AsyncPolicy
+		want    string
+		dropped uint64
+	}{
+		{"drop newest", AsyncDropNewest, "0123", 2},
+		{"drop oldest", AsyncDropOldest, "0345", 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := newBlockingWriter()
+			async := AsyncOutput(Output{Writer: w, Format: testFormat},
+				WithAsyncQueueSize(3),
+				WithAsyncPolicy(c.policy))
+			ctx := Context(context.Background(), WithOutputs(async.Output()))
+
+			Printf(ctx, "0")
+			<-w.started // first entry dequeued, writer blocked
+			for _, msg := range []string{"1", "2", "3", "4", "5"} {
+				Print(ctx, KV{"msg", msg})
+			}
+			close(w.unblock)
+			require.NoError(t, async.Close(context.Background()))
+
+			assert.Equal(t, c.want, w.String())
+			assert.Equal(t, c.dropped, async.Dropped())
+		})
+	}
+}
+
+func TestAsyncOutputBlock(t *testing.T) {
+	w := newBlockingWriter()
+	async := AsyncOutput(Output{Writer: w, Format: testFormat},
+		WithAsyncQueueSize(1),
+		WithAsyncPolicy(AsyncBlock))
+	ctx := Context(context.Background(), WithOutputs(async.Output()))
+
+	Printf(ctx, "0")
+	<-w.started
+	Printf(ctx, "1") // queued
+	done := make(chan struct{})
+	go func() {
+		Printf(ctx, "2") // blocks until the writer is unblocked
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected log call to block")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(w.unblock)
+	<-done
+	require.NoError(t, async.Close(context.Background()))
+
+	assert.Equal(t, "012", w.String())
+	assert.Zero(t, async.Dropped())
+}
+
+func TestAsyncOutputCloseTimeout(t *testing.T) {
+	w := newBlockingWriter()
+	async := AsyncOutput(Output{Writer: w, Format: testFormat})
+	ctx := Context(context.Background(), WithOutputs(async.Output()))
+	Printf(ctx, "0")
+	<-w.started
+
+	cctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, async.Close(cctx), context.DeadlineExceeded)
+
+	close(w.unblock)
+	require.NoError(t, async.Close(context.Background()))
+	assert.Equal(t, "0", w.String())
+}
+
+func TestAsyncOutputHandle(t *testing.T) {
+	var msgs []string
+	async := AsyncOutput(Output{Handle: func(e *Entry) error {
+		msgs = append(msgs, e.KeyVals[0].V.(string))
+		return nil
+	}})
+	ctx := Context(context.Background(), WithOutputs(async.Output()))
+	Printf(ctx, "hello")
+	Printf(ctx, "world")
+	require.NoError(t, async.Close(context.Background()))
+	assert.Equal(t, "hello world", strings.Join(msgs, " "))
+}
+
+func TestAsyncOutputFilters(t *testing.T) {
+	var buf Buffer
+	async := AsyncOutput(Output{Writer: &buf, Format: testFormat, MinSeverity: SeverityWarn})
+	ctx := Context(context.Background(), WithOutputs(async.Output()))
+	Printf(ctx, "info")
+	Errorf(ctx, nil, "error")
+	require.NoError(t, async.Close(context.Background()))
+	assert.Equal(t, "error", buf.String())
+	assert.Zero(t, async.Dropped())
+}
+
+func TestAsyncOutputErrorHandler(t *testing.T) {
+	var (
+		lock sync.Mutex
+		errs []error
+	)
+	onError := func(_ *Entry, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		errs = append(errs, err)
+	}
+	w := newBlockingWriter()
+	async := AsyncOutput(Output{Handle: func(e *Entry) error {
+		w.Write(nil) // nolint: errcheck
+		return errors.New("write failed")
+	}}, WithAsyncQueueSize(1), WithAsyncErrorHandler(onError))
+	ctx := Context(context.Background(), WithOutputs(async.Output()))
+
+	Printf(ctx, "0")
+	<-w.started
+	Printf(ctx, "1") // queued
+	Printf(ctx, "2") // dropped
+	close(w.unblock)
+	require.NoError(t, async.Close(context.Background()))
+	Printf(ctx, "3") // closed
+
+	assert.Equal(t, []error{ErrAsyncQueueFull, errors.New("write failed"), errors.New("write failed"), ErrAsyncWriterClosed}, errs)
+	assert.Equal(t, uint64(2), async.Dropped())
+}
+
+func TestAsyncOutputMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	w := newBlockingWriter()
+	async := AsyncOutput(Output{Writer: w, Format: testFormat}, WithAsyncQueueSize(1))
+	ctx := Context(context.Background(), WithOutputs(async.Output()), WithMeter(mp))
+
+	Printf(ctx, "0")
+	<-w.started
+	Printf(ctx, "1") // queued
+	Printf(ctx, "2") // dropped
+	close(w.unblock)
+	require.NoError(t, async.Close(context.Background()))
+
+	counts := collectCounts(t, reader)
+	assert.Equal(t, map[string]int64{"0": 1}, counts[WriteErrorsMetric+"/"+OutputAttributeKey])
+	assert.Equal(t, "01", w.String())
+}
@@ -0,0 +1,76 @@
+package log
+
+import "sync/atomic"
+
+// buffer holds the log entries of a logger until they are flushed. A buffer
+// may be bounded in which case it keeps the first entries in head and the most
+// recent entries in tail, a ring buffer. Entries in between are dropped.
+type buffer struct {
+	head    []*Entry
+	tail    []*Entry
+	start   int
+	dropped int
+}
+
+// droppedEntries counts the number of buffered entries dropped because the
+// buffer was full.
+var droppedEntries atomic.Uint64
+
+// DroppedBufferedEntries returns the total number of log entries dropped by
+// the process because a bounded log buffer was full (see
+// WithMaxBufferedEntries).
+func DroppedBufferedEntries() uint64 {
+	return droppedEntries.Load()
+}
+
+// add appends e to the buffer. If last is greater than zero the buffer is
+// bounded: it keeps the first entries up to first and the last entries up to
+// last. add returns true if an entry was dropped to make room for e.
+func (b *buffer) add(e *Entry, first, last int) bool {
+	if last <= 0 || len(b.head) < first {
+		b.head = append(b.head, e)
+		return false
+	}
+	if len(b.tail) < last {
+		b.tail = append(b.tail, e)
+		return false
+	}
+	b.tail[b.start] = e
+	b.start = (b.start + 1) % len(b.tail)
+	b.dropped++
+	droppedEntries.Add(1)
+	return true
+}
+
+// len returns the number of buffered entries.
+func (b *buffer) len() int {
+	return len(b.head) + len(b.tail)
+}
+
+// contents returns the first entries, the number of dropped entries and the
+// last entries in order.
+func (b *buffer) contents() (head []*Entry, dropped int, tail []*Entry) {
+	if b.start == 0 {
+		return b.head, b.dropped, b.tail
+	}
+	tail = make([]*Entry, 0, len(b.tail))
+	tail = append(tail, b.tail[b.start:]...)
+	tail = append(tail, b.tail[:b.start]...)
+	return b.head, b.dropped, tail
+}
+
+// list returns the buffered entries in order.
+func (b *buffer) list() []*Entry {
+	head, _, tail := b.contents()
+	if len(tail) == 0 {
+		return head
+	}
+	res := make([]*Entry, 0, len(head)+len(tail))
+	res = append(res, head...)
+	return append(res, tail...)
+}
+
+// reset empties the buffer, freeing up memory.
+func (b *buffer) reset() {
+	*b = buffer{}
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferUnbounded(t *testing.T) {
+	var b buffer
+	for i := range 10 {
+		b.add(&Entry{KeyVals: kvList{{"i", i}}}, 0, 0)
+	}
+	head, dropped, tail := b.contents()
+	assert.Len(t, head, 10)
+	assert.Zero(t, dropped)
+	assert.Empty(t, tail)
+}
+
+func TestBufferBounded(t *testing.T) {
+	cases := []struct {
+		name        string
+		first, last int
+		want        []int
+		dropped     int
+	}{
+		{"last only", 0, 3, []int{7, 8, 9}, 7},
+		{"first and last", 2, 3, []int{0, 1, 7, 8, 9}, 5},
+		{"not full", 4, 8, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b buffer
+			for i := range 10 {
+				b.add(&Entry{KeyVals: kvList{{"i", i}}}, c.first, c.last)
+			}
+			_, dropped, _ := b.contents()
+			assert.Equal(t, c.dropped, dropped)
+			list := b.list()
+			require.Len(t, list, len(c.want))
+			for i, e := range list {
+				assert.Equal(t, c.want[i], e.KeyVals[0].V)
+			}
+		})
+	}
+}
+
+func TestMaxBufferedEntries(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithMaxBufferedEntries(2),
+		WithKeepFirstBufferedEntries(1),
+	)
+	ctx = With(ctx, KV{"svc", "test"})
+	before := DroppedBufferedEntries()
+	for i := range 6 {
+		Info(ctx, KV{"i", strconv.Itoa(i)})
+	}
+	assert.Len(t, entries(ctx), 3)
+	assert.Equal(t, uint64(3), DroppedBufferedEntries()-before)
+
+	FlushAndDisableBuffering(ctx)
+
+	want := "time=2022-02-22T17:00:00Z level=info svc=test i=0\n" +
+		"time=2022-02-22T17:00:00Z level=warn svc=test msg=\"dropped buffered log entries\" log.dropped=3\n" +
+		"time=2022-02-22T17:00:00Z level=info svc=test i=4\n" +
+		"time=2022-02-22T17:00:00Z level=info svc=test i=5\n"
+	assert.Equal(t, want, buf.String())
+}
@@ -0,0 +1,177 @@
+package log
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type (
+	// CaptureMode controls when the HTTP middleware logs the headers and
+	// bodies captured with WithRequestHeaders, WithResponseHeaders,
+	// WithRequestBody and WithResponseBody.
+	CaptureMode int
+
+	// captureOptions lists the request and response details captured by the
+	// HTTP middleware.
+	captureOptions struct {
+		mode            CaptureMode
+		requestHeaders  []string
+		responseHeaders []string
+		requestBody     int
+		responseBody    int
+	}
+
+	// bodyCapture is an io.ReadCloser that copies up to max bytes read from
+	// the underlying body.
+	bodyCapture struct {
+		io.ReadCloser
+		buf limitedBuffer
+	}
+
+	// limitedBuffer is a byte buffer that discards writes past max bytes.
+	limitedBuffer struct {
+		data []byte
+		max  int
+	}
+)
+
+const (
+	// CaptureAlways logs the captured details with every request.
+	CaptureAlways CaptureMode = iota
+	// CaptureOnError logs the captured details only when the response status
+	// code is 400 or greater.
+	CaptureOnError
+	// CaptureOnDebug logs the captured details only when debug logs are
+	// enabled in the request context.
+	CaptureOnDebug
+)
+
+// WithRequestHeaders returns a HTTP middleware option that logs the values of
+// the given request headers under HTTPRequestHeaderKeyPrefix followed by the
+// lowercase header name. Multiple values are joined with commas.
+func WithRequestHeaders(names ...string) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.capture.requestHeaders = append(o.capture.requestHeaders, canonicalHeaders(names)...)
+	}
+}
+
+// WithResponseHeaders returns a HTTP middleware option that logs the values of
+// the given response headers under HTTPResponseHeaderKeyPrefix followed by
+// the lowercase header name. Multiple values are joined with commas.
+func WithResponseHeaders(names ...string) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.capture.responseHeaders = append(o.capture.responseHeaders, canonicalHeaders(names)...)
+	}
+}
+
+// WithRequestBody returns a HTTP middleware option that logs up to max bytes
+// of the request body under HTTPRequestBodyKey. The body is captured as the
+// handler reads it so that only the bytes read by the handler are logged.
+func WithRequestBody(max int) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.capture.requestBody = max
+	}
+}
+
+// WithResponseBody returns a HTTP middleware option that logs up to max bytes
+// of the response body under HTTPResponseBodyKey. The body is captured as the
+// handler writes it so that streaming responses are not delayed.
+func WithResponseBody(max int) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.capture.responseBody = max
+	}
+}
+
+// WithCaptureMode returns a HTTP middleware option that sets when the
+// captured headers and bodies are logged. The default is CaptureAlways.
+func WithCaptureMode(mode CaptureMode) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.capture.mode = mode
+	}
+}
+
+// enabled returns true if any request or response detail is captured.
+func (o *captureOptions) enabled() bool {
+	return len(o.requestHeaders) > 0 || len(o.responseHeaders) > 0 ||
+		o.requestBody > 0 || o.responseBody > 0
+}
+
+// start prepares the capture of the request and response details. It returns
+// the captured request body, nil if the request body is not captured.
+func (o *captureOptions) start(ctx context.Context, req *http.Request, rw *responseCapture) *bodyCapture {
+	if o.mode == CaptureOnDebug && !DebugEnabled(ctx) {
+		return nil
+	}
+	if o.responseBody > 0 {
+		rw.body = &limitedBuffer{max: o.responseBody}
+	}
+	if o.requestBody <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	body := &bodyCapture{ReadCloser: req.Body, buf: limitedBuffer{max: o.requestBody}}
+	req.Body = body
+	return body
+}
+
+// keyvals returns the captured details to log given the request headers, the
+// captured request body and the response.
+func (o *captureOptions) keyvals(ctx context.Context, reqHeader http.Header, reqBody *bodyCapture, rw *responseCapture) []Fielder {
+	switch o.mode {
+	case CaptureOnError:
+		if rw.StatusCode < 400 {
+			return nil
+		}
+	case CaptureOnDebug:
+		if !DebugEnabled(ctx) {
+			return nil
+		}
+	}
+	var kvs []Fielder
+	kvs = appendHeaders(kvs, HTTPRequestHeaderKeyPrefix, reqHeader, o.requestHeaders)
+	if reqBody != nil {
+		kvs = append(kvs, KV{K: HTTPRequestBodyKey, V: string(reqBody.buf.data)})
+	}
+	kvs = appendHeaders(kvs, HTTPResponseHeaderKeyPrefix, rw.Header(), o.responseHeaders)
+	if rw.body != nil {
+		kvs = append(kvs, KV{K: HTTPResponseBodyKey, V: string(rw.body.data)})
+	}
+	return kvs
+}
+
+// Read reads from the underlying body and records the bytes read.
+func (b *bodyCapture) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.write(p[:n])
+	return n, err
+}
+
+// write appends p to the buffer up to its maximum size.
+func (b *limitedBuffer) write(p []byte) {
+	if room := b.max - len(b.data); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		b.data = append(b.data, p...)
+	}
+}
+
+// appendHeaders appends the values of the given headers found in h to kvs.
+func appendHeaders(kvs []Fielder, prefix string, h http.Header, names []string) []Fielder {
+	for _, name := range names {
+		if vals := h.Values(name); len(vals) > 0 {
+			kvs = append(kvs, KV{K: prefix + strings.ToLower(name), V: strings.Join(vals, ",")})
+		}
+	}
+	return kvs
+}
+
+// canonicalHeaders returns the canonical form of the given header names.
+func canonicalHeaders(names []string) []string {
+	res := make([]string, len(names))
+	for i, name := range names {
+		res[i] = http.CanonicalHeaderKey(name)
+	}
+	return res
+}
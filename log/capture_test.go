@@ -0,0 +1,113 @@
+package log
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPCapture(t *testing.T) {
+	echo := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Add("X-Trace", "a")
+			w.Header().Add("X-Trace", "b")
+			w.WriteHeader(status)
+			w.Write([]byte("echo: ")) // nolint: errcheck
+			w.Write(body)             // nolint: errcheck
+			w.(http.Flusher).Flush()
+			w.Write([]byte(" (done)")) // nolint: errcheck
+		}
+	}
+	all := []HTTPLogOption{
+		WithRequestHeaders("content-type", "x-missing"),
+		WithResponseHeaders("X-Trace"),
+		WithRequestBody(5),
+		WithResponseBody(8),
+	}
+	captured := kvList{
+		{HTTPRequestHeaderKeyPrefix + "content-type", "application/json"},
+		{HTTPRequestBodyKey, `{"a":`},
+		{HTTPResponseHeaderKeyPrefix + "x-trace", "a,b"},
+		{HTTPResponseBodyKey, `echo: {"`},
+	}
+	cases := []struct {
+		name   string
+		opts   []HTTPLogOption
+		logOpt LogOption
+		status int
+		want   kvList
+	}{
+		{"none", nil, nil, http.StatusOK, nil},
+		{"always", all, nil, http.StatusOK, captured},
+		{"headers only", all[:2], nil, http.StatusOK, kvList{captured[0], captured[2]}},
+		{"on error success", append(all, WithCaptureMode(CaptureOnError)), nil, http.StatusOK, nil},
+		{"on error failure", append(all, WithCaptureMode(CaptureOnError)), nil, http.StatusBadRequest, captured},
+		{"on debug disabled", append(all, WithCaptureMode(CaptureOnDebug)), nil, http.StatusOK, nil},
+		{"on debug enabled", append(all, WithCaptureMode(CaptureOnDebug)), WithDebug(), http.StatusOK, captured},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var logged []*Entry
+			opts := []LogOption{WithOutputs(Output{Handle: func(e *Entry) error {
+				logged = append(logged, e)
+				return nil
+			}})}
+			if c.logOpt != nil {
+				opts = append(opts, c.logOpt)
+			}
+			ctx := Context(context.Background(), opts...)
+			handler := HTTP(ctx, append(c.opts, WithDisableRequestID())...)(echo(c.status))
+
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader(`{"a":"b"}`))
+			req.Header.Set("Content-Type", "application/json")
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.status, rec.Code)
+			assert.Equal(t, `echo: {"a":"b"} (done)`, rec.Body.String(), "body must be left intact")
+			require.Len(t, logged, 2)
+			end := logged[1].KeyVals
+			assert.Equal(t, KV{MessageKey, "end"}, end[0])
+			var got kvList
+			if len(end) > 6 {
+				got = end[6:]
+			}
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestHTTPCaptureDisabledRequestLogging(t *testing.T) {
+	var logged []*Entry
+	ctx := Context(context.Background(), WithOutputs(Output{Handle: func(e *Entry) error {
+		logged = append(logged, e)
+		return nil
+	}}))
+	var read string
+	handler := HTTP(ctx, WithRequestBody(10), WithDisableRequestLogging())(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		read = string(b)
+	}))
+
+	req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("payload"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "payload", read)
+	assert.Empty(t, logged)
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := limitedBuffer{max: 4}
+	b.write([]byte("ab"))
+	b.write([]byte("cde"))
+	b.write([]byte("f"))
+	assert.Equal(t, "abcd", string(b.data))
+}
@@ -0,0 +1,328 @@
+package log
+
+import (
+	"encoding/binary"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ECSVersion is the version of the Elastic Common Schema written by FormatECS
+// in the ecs.version field.
+var ECSVersion = "8.11.0"
+
+// FormatGCP returns a log formatter that prints entries using the JSON
+// structure expected by Google Cloud Logging:
+//
+//	{
+//	  "timestamp": "TIMESTAMP",                              // RFC3339 with nanoseconds
+//	  "severity": "SEVERITY",                                // DEBUG, INFO, WARNING or ERROR
+//	  "message": "MESSAGE",                                  // value of MessageKey
+//	  "logging.googleapis.com/trace": "projects/PROJECT/traces/TRACE",
+//	  "logging.googleapis.com/spanId": "SPAN",
+//	  "logging.googleapis.com/trace_sampled": true,
+//	  "key1": "val1",                                        // entry key/value pairs
+//	  ...
+//	  "httpRequest": {                                       // HTTP request keys
+//	    "requestMethod": "GET",
+//	    "requestUrl": "/path",
+//	    "status": 200,
+//	    "remoteIp": "127.0.0.1",
+//	    "latency": "0.042s",
+//	    "responseSize": "1024"
+//	  }
+//	}
+//
+// The trace and span IDs are read from the span context of the entry or from
+// the TraceIDKey and SpanIDKey key/value pairs. trace_sampled is only written
+// if the entry has a span context as the sampling decision is not known
+// otherwise. The values of the HTTPMethodKey, HTTPURLKey, HTTPStatusKey,
+// HTTPFromKey, HTTPDurationKey and HTTPBytesKey keys are written to the
+// httpRequest object.
+func FormatGCP(projectID string) FormatFunc {
+	tracePrefix := "projects/" + projectID + "/traces/"
+	return func(e *Entry) []byte {
+		b := make([]byte, 0, 512)
+		b = append(b, '{')
+		b = appendJSONKeyValue(b, "timestamp", e.Time.UTC().Format(time.RFC3339Nano))
+		b = append(b, ',')
+		b = appendJSONKeyValue(b, "severity", gcpSeverity(e.Severity))
+		if msg, ok := entryMessage(e); ok {
+			b = append(b, ',')
+			b = appendJSONKeyValue(b, "message", msg)
+		}
+		if sc := entrySpanContext(e); sc.HasTraceID() {
+			b = append(b, ',')
+			b = appendJSONKeyValue(b, "logging.googleapis.com/trace", tracePrefix+sc.TraceID().String())
+			if sc.HasSpanID() {
+				b = append(b, ',')
+				b = appendJSONKeyValue(b, "logging.googleapis.com/spanId", sc.SpanID().String())
+			}
+			if e.spanContext.IsValid() {
+				b = append(b, ',')
+				b = appendJSONKeyValue(b, "logging.googleapis.com/trace_sampled", sc.IsSampled())
+			}
+		}
+		var req []byte
+		for _, kv := range e.KeyVals {
+			switch kv.K {
+			case MessageKey, TraceIDKey, SpanIDKey:
+				continue
+			case HTTPMethodKey:
+				req = appendJSONField(req, "requestMethod", kv.V)
+			case HTTPURLKey:
+				req = appendJSONField(req, "requestUrl", kv.V)
+			case HTTPStatusKey:
+				req = appendJSONField(req, "status", kv.V)
+			case HTTPFromKey:
+				req = appendJSONField(req, "remoteIp", kv.V)
+			case HTTPDurationKey:
+				if ms, ok := toInt64(kv.V); ok {
+					req = appendJSONField(req, "latency", strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)+"s")
+				} else {
+					req = appendJSONField(req, "latency", kv.V)
+				}
+			case HTTPBytesKey:
+				if n, ok := toInt64(kv.V); ok {
+					req = appendJSONField(req, "responseSize", strconv.FormatInt(n, 10))
+				} else {
+					req = appendJSONField(req, "responseSize", kv.V)
+				}
+			default:
+				b = append(b, ',')
+				b = appendJSONKeyValue(b, kv.K, kv.V)
+			}
+		}
+		if len(req) > 0 {
+			b = append(b, `,"httpRequest":{`...)
+			b = append(b, req...)
+			b = append(b, '}')
+		}
+		b = append(b, "}\n"...)
+		return b
+	}
+}
+
+// FormatDatadog is a log formatter that prints entries using the JSON
+// attributes expected by Datadog:
+//
+//	{
+//	  "timestamp": "TIMESTAMP",   // RFC3339 with nanoseconds
+//	  "status": "SEVERITY",       // debug, info, warn or error
+//	  "message": "MESSAGE",       // value of MessageKey
+//	  "dd.trace_id": "TRACE",     // lower 64 bits of the trace ID in decimal
+//	  "dd.span_id": "SPAN",       // span ID in decimal
+//	  "key1": "val1",             // entry key/value pairs
+//	  ...
+//	}
+//
+// The trace and span IDs are read from the span context of the entry or from
+// the TraceIDKey and SpanIDKey key/value pairs. HTTP and error keys are renamed
+// to the corresponding Datadog standard attributes: http.method, http.url,
+// http.status_code, network.client.ip, duration (in nanoseconds),
+// network.bytes_written and error.message.
+func FormatDatadog(e *Entry) []byte {
+	b := make([]byte, 0, 512)
+	b = append(b, '{')
+	b = appendJSONKeyValue(b, "timestamp", e.Time.UTC().Format(time.RFC3339Nano))
+	b = append(b, ',')
+	b = appendJSONKeyValue(b, "status", e.Severity.String())
+	if msg, ok := entryMessage(e); ok {
+		b = append(b, ',')
+		b = appendJSONKeyValue(b, "message", msg)
+	}
+	if sc := entrySpanContext(e); sc.HasTraceID() {
+		tid := sc.TraceID()
+		b = append(b, ',')
+		b = appendJSONKeyValue(b, "dd.trace_id", strconv.FormatUint(binary.BigEndian.Uint64(tid[8:]), 10))
+		if sc.HasSpanID() {
+			sid := sc.SpanID()
+			b = append(b, ',')
+			b = appendJSONKeyValue(b, "dd.span_id", strconv.FormatUint(binary.BigEndian.Uint64(sid[:]), 10))
+		}
+	}
+	for _, kv := range e.KeyVals {
+		key := kv.K
+		val := kv.V
+		switch kv.K {
+		case MessageKey, TraceIDKey, SpanIDKey:
+			continue
+		case HTTPMethodKey:
+			key = "http.method"
+		case HTTPURLKey:
+			key = "http.url"
+		case HTTPStatusKey:
+			key = "http.status_code"
+		case HTTPFromKey:
+			key = "network.client.ip"
+		case HTTPDurationKey:
+			key = "duration"
+			if ms, ok := toInt64(kv.V); ok {
+				val = ms * int64(time.Millisecond)
+			}
+		case HTTPBytesKey:
+			key = "network.bytes_written"
+		case ErrorMessageKey:
+			key = "error.message"
+		}
+		b = append(b, ',')
+		b = appendJSONKeyValue(b, key, val)
+	}
+	b = append(b, "}\n"...)
+	return b
+}
+
+// FormatECS is a log formatter that prints entries using the Elastic Common
+// Schema (ECS):
+//
+//	{
+//	  "@timestamp": "TIMESTAMP",  // RFC3339 with milliseconds
+//	  "log.level": "SEVERITY",    // debug, info, warn or error
+//	  "message": "MESSAGE",       // value of MessageKey
+//	  "ecs.version": "8.11.0",
+//	  "trace.id": "TRACE",
+//	  "span.id": "SPAN",
+//	  "key1": "val1",             // entry key/value pairs
+//	  ...
+//	}
+//
+// The trace and span IDs are read from the span context of the entry or from
+// the TraceIDKey and SpanIDKey key/value pairs. HTTP, request ID and error keys
+// are renamed to the corresponding ECS fields: http.request.method, url.full,
+// http.response.status_code, client.address, event.duration (in nanoseconds),
+// http.response.body.bytes, http.request.id and error.message.
+func FormatECS(e *Entry) []byte {
+	b := make([]byte, 0, 512)
+	b = append(b, '{')
+	b = appendJSONKeyValue(b, "@timestamp", e.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	b = append(b, ',')
+	b = appendJSONKeyValue(b, "log.level", e.Severity.String())
+	if msg, ok := entryMessage(e); ok {
+		b = append(b, ',')
+		b = appendJSONKeyValue(b, "message", msg)
+	}
+	b = append(b, ',')
+	b = appendJSONKeyValue(b, "ecs.version", ECSVersion)
+	if sc := entrySpanContext(e); sc.HasTraceID() {
+		b = append(b, ',')
+		b = appendJSONKeyValue(b, "trace.id", sc.TraceID().String())
+		if sc.HasSpanID() {
+			b = append(b, ',')
+			b = appendJSONKeyValue(b, "span.id", sc.SpanID().String())
+		}
+	}
+	for _, kv := range e.KeyVals {
+		key := kv.K
+		val := kv.V
+		switch kv.K {
+		case MessageKey, TraceIDKey, SpanIDKey:
+			continue
+		case HTTPMethodKey:
+			key = "http.request.method"
+		case HTTPURLKey:
+			key = "url.full"
+		case HTTPStatusKey:
+			key = "http.response.status_code"
+		case HTTPFromKey:
+			key = "client.address"
+		case HTTPDurationKey:
+			key = "event.duration"
+			if ms, ok := toInt64(kv.V); ok {
+				val = ms * int64(time.Millisecond)
+			}
+		case HTTPBytesKey:
+			key = "http.response.body.bytes"
+		case RequestIDKey:
+			key = "http.request.id"
+		case ErrorMessageKey:
+			key = "error.message"
+		}
+		b = append(b, ',')
+		b = appendJSONKeyValue(b, key, val)
+	}
+	b = append(b, "}\n"...)
+	return b
+}
+
+// gcpSeverity returns the Google Cloud Logging severity for sev.
+func gcpSeverity(sev Severity) string {
+	switch sev {
+	case SeverityDebug:
+		return "DEBUG"
+	case SeverityInfo:
+		return "INFO"
+	case SeverityWarn:
+		return "WARNING"
+	case SeverityError:
+		return "ERROR"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// entryMessage returns the value of the first MessageKey key/value pair of e.
+func entryMessage(e *Entry) (any, bool) {
+	for _, kv := range e.KeyVals {
+		if kv.K == MessageKey {
+			return kv.V, true
+		}
+	}
+	return nil, false
+}
+
+// entrySpanContext returns the span context of e. If e does not have a valid
+// span context the trace and span IDs are read from the TraceIDKey and
+// SpanIDKey key/value pairs instead.
+func entrySpanContext(e *Entry) trace.SpanContext {
+	if e.spanContext.IsValid() {
+		return e.spanContext
+	}
+	var cfg trace.SpanContextConfig
+	for _, kv := range e.KeyVals {
+		s, ok := stringValue(kv.V)
+		if !ok {
+			continue
+		}
+		switch kv.K {
+		case TraceIDKey:
+			if tid, err := trace.TraceIDFromHex(s); err == nil {
+				cfg.TraceID = tid
+			}
+		case SpanIDKey:
+			if sid, err := trace.SpanIDFromHex(s); err == nil {
+				cfg.SpanID = sid
+			}
+		}
+	}
+	return trace.NewSpanContext(cfg)
+}
+
+// appendJSONField appends a JSON object field to b, separated from the
+// previous field with a comma if b is not empty.
+func appendJSONField(b []byte, key string, value any) []byte {
+	if len(b) > 0 {
+		b = append(b, ',')
+	}
+	return appendJSONKeyValue(b, key, value)
+}
+
+// toInt64 returns v as an int64 if v is an integer.
+func toInt64(v any) (int64, bool) {
+	switch n := plainValue(v).(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true // nolint: gosec
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true // nolint: gosec
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,133 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceID = "0af7651916cd43dd8448eb211c80319c"
+	testSpanID  = "b7ad6b7169203331"
+)
+
+func TestFormatGCP(t *testing.T) {
+	cases := []struct {
+		name    string
+		sev     Severity
+		keyvals []KV
+		want    string
+	}{
+		{"message", SeverityInfo, []KV{{MessageKey, "hello"}, {"k", "v"}},
+			`{"timestamp":"2022-02-22T17:00:00Z","severity":"INFO","message":"hello","k":"v"}` + "\n"},
+		{"severity", SeverityWarn, nil,
+			`{"timestamp":"2022-02-22T17:00:00Z","severity":"WARNING"}` + "\n"},
+		{"trace", SeverityError, []KV{{TraceIDKey, testTraceID}, {SpanIDKey, testSpanID}},
+			`{"timestamp":"2022-02-22T17:00:00Z","severity":"ERROR",` +
+				`"logging.googleapis.com/trace":"projects/proj/traces/` + testTraceID + `",` +
+				`"logging.googleapis.com/spanId":"` + testSpanID + `"}` + "\n"},
+		{"http", SeverityInfo, []KV{
+			{MessageKey, "end"}, {HTTPMethodKey, "GET"}, {HTTPURLKey, "/path"}, {HTTPStatusKey, 200},
+			{HTTPFromKey, "127.0.0.1"}, {HTTPDurationKey, int64(1500)}, {HTTPBytesKey, int64(42)},
+			{RequestIDKey, "id"}},
+			`{"timestamp":"2022-02-22T17:00:00Z","severity":"INFO","message":"end","request_id":"id",` +
+				`"httpRequest":{"requestMethod":"GET","requestUrl":"/path","status":200,` +
+				`"remoteIp":"127.0.0.1","latency":"1.5s","responseSize":"42"}}` + "\n"},
+	}
+	format := FormatGCP("proj")
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &Entry{Time: timeNow(), Severity: c.sev, KeyVals: c.keyvals}
+			assert.Equal(t, c.want, string(format(e)))
+		})
+	}
+}
+
+func TestFormatDatadog(t *testing.T) {
+	cases := []struct {
+		name    string
+		sev     Severity
+		keyvals []KV
+		want    string
+	}{
+		{"message", SeverityInfo, []KV{{MessageKey, "hello"}, {"k", "v"}},
+			`{"timestamp":"2022-02-22T17:00:00Z","status":"info","message":"hello","k":"v"}` + "\n"},
+		{"trace", SeverityDebug, []KV{{TraceIDKey, testTraceID}, {SpanIDKey, testSpanID}},
+			`{"timestamp":"2022-02-22T17:00:00Z","status":"debug",` +
+				`"dd.trace_id":"9532127138774266268","dd.span_id":"13235353014750950193"}` + "\n"},
+		{"http", SeverityError, []KV{
+			{HTTPMethodKey, "GET"}, {HTTPURLKey, "/path"}, {HTTPStatusKey, 500},
+			{HTTPFromKey, "127.0.0.1"}, {HTTPDurationKey, int64(2)}, {HTTPBytesKey, int64(42)},
+			{ErrorMessageKey, "boom"}},
+			`{"timestamp":"2022-02-22T17:00:00Z","status":"error","http.method":"GET","http.url":"/path",` +
+				`"http.status_code":500,"network.client.ip":"127.0.0.1","duration":2000000,` +
+				`"network.bytes_written":42,"error.message":"boom"}` + "\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &Entry{Time: timeNow(), Severity: c.sev, KeyVals: c.keyvals}
+			assert.Equal(t, c.want, string(FormatDatadog(e)))
+		})
+	}
+}
+
+func TestFormatECS(t *testing.T) {
+	cases := []struct {
+		name    string
+		sev     Severity
+		keyvals []KV
+		want    string
+	}{
+		{"message", SeverityInfo, []KV{{MessageKey, "hello"}, {"k", "v"}},
+			`{"@timestamp":"2022-02-22T17:00:00.000Z","log.level":"info","message":"hello","ecs.version":"8.11.0","k":"v"}` + "\n"},
+		{"trace", SeverityWarn, []KV{{TraceIDKey, testTraceID}, {SpanIDKey, testSpanID}},
+			`{"@timestamp":"2022-02-22T17:00:00.000Z","log.level":"warn","ecs.version":"8.11.0",` +
+				`"trace.id":"` + testTraceID + `","span.id":"` + testSpanID + `"}` + "\n"},
+		{"http", SeverityError, []KV{
+			{HTTPMethodKey, "GET"}, {HTTPURLKey, "/path"}, {HTTPStatusKey, 500},
+			{HTTPFromKey, "127.0.0.1"}, {HTTPDurationKey, int64(2)}, {HTTPBytesKey, int64(42)},
+			{RequestIDKey, "id"}, {ErrorMessageKey, "boom"}},
+			`{"@timestamp":"2022-02-22T17:00:00.000Z","log.level":"error","ecs.version":"8.11.0",` +
+				`"http.request.method":"GET","url.full":"/path","http.response.status_code":500,` +
+				`"client.address":"127.0.0.1","event.duration":2000000,"http.response.body.bytes":42,` +
+				`"http.request.id":"id","error.message":"boom"}` + "\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &Entry{Time: timeNow(), Severity: c.sev, KeyVals: c.keyvals}
+			assert.Equal(t, c.want, string(FormatECS(e)))
+		})
+	}
+}
+
+func TestCloudFormatsCustomKeys(t *testing.T) {
+	restore := HTTPStatusKey
+	defer func() { HTTPStatusKey = restore }()
+	HTTPStatusKey = "status"
+
+	e := &Entry{Time: timeNow(), Severity: SeverityInfo, KeyVals: []KV{{"status", 200}}}
+	assert.Contains(t, string(FormatECS(e)), `"http.response.status_code":200`)
+	assert.Contains(t, string(FormatDatadog(e)), `"http.status_code":200`)
+	assert.Contains(t, string(FormatGCP("proj")(e)), `"httpRequest":{"status":200}`)
+}
+
+func TestCloudFormatsSpanContext(t *testing.T) {
+	tid, _ := trace.TraceIDFromHex(testTraceID)
+	sid, _ := trace.SpanIDFromHex(testSpanID)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	var entry *Entry
+	ctx = Context(ctx, WithOutputs(Output{Handle: func(e *Entry) error { entry = e; return nil }}))
+	Error(ctx, errors.New("boom"), KV{"msg", "failed"})
+
+	assert.Equal(t, `{"timestamp":"2022-02-22T17:00:00Z","severity":"ERROR","message":"failed",`+
+		`"logging.googleapis.com/trace":"projects/proj/traces/`+testTraceID+`",`+
+		`"logging.googleapis.com/spanId":"`+testSpanID+`",`+
+		`"logging.googleapis.com/trace_sampled":true,"err":"boom"}`+"\n", string(FormatGCP("proj")(entry)))
+	assert.Contains(t, string(FormatDatadog(entry)), `"dd.trace_id":"9532127138774266268"`)
+	assert.Contains(t, string(FormatECS(entry)), `"trace.id":"`+testTraceID+`"`)
+}
@@ -12,8 +12,9 @@ func Context(ctx context.Context, opts ...LogOption) context.Context {
 	if !ok {
 		l = &logger{options: defaultOptions()}
 	}
-	l.lock.Lock()
-	defer l.lock.Unlock()
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
 	for _, opt := range opts {
 		opt(l.options)
 	}
@@ -45,14 +46,9 @@ func MustContainLogger(logCtx context.Context) {
 	}
 }
 
-// DebugEnabled returns true if the given context has debug logging enabled.
+// DebugEnabled returns true if the given context has debug logging enabled,
+// either with WithDebug or with a LevelVar set to SeverityDebug. The default
+// logger set with SetDefault is used if the context is not initialized.
 func DebugEnabled(ctx context.Context) bool {
-	v := ctx.Value(ctxLogger)
-	if v == nil {
-		return false
-	}
-	l := v.(*logger)
-	l.lock.Lock()
-	defer l.lock.Unlock()
-	return l.options.debug
+	return enabled(ctx, SeverityDebug)
 }
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// defaultLogger is the logger used by contexts that were not initialized
+	// with Context, nil if there is none.
+	defaultLogger atomic.Pointer[logger]
+	// strict is true if logging with uninitialized contexts is reported.
+	strict atomic.Bool
+	// uninitializedCalls counts the logging calls made with uninitialized
+	// contexts.
+	uninitializedCalls atomic.Uint64
+	// reportedCallers records the caller locations already reported in
+	// strict mode.
+	reportedCallers sync.Map
+)
+
+// Be kind to tests
+var strictOutput io.Writer = os.Stderr
+
+// SetDefault sets the logger of the given log context as the process-wide
+// default logger. The default logger is used by the logging functions called
+// with contexts that were not initialized with Context or WithContext, for
+// example contexts created with context.Background in background goroutines.
+// Without a default logger such calls do nothing. With also derives from the
+// default logger when the given context is not initialized.
+//
+// The default logger uses the options and key/value pairs of the log context
+// at the time SetDefault is called. It never buffers log entries: they are
+// written as soon as they are logged. Calling SetDefault with a context that
+// is not initialized removes the default logger.
+//
+// Usage:
+//
+//	ctx := log.Context(context.Background(), log.WithFormat(log.FormatJSON))
+//	log.SetDefault(log.With(ctx, log.KV{"svc", "worker"}))
+//	go func() {
+//	    log.Errorf(context.Background(), err, "background job failed") // logged
+//	}()
+func SetDefault(ctx context.Context) {
+	l, ok := ctx.Value(ctxLogger).(*logger)
+	if !ok {
+		defaultLogger.Store(nil)
+		return
+	}
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	opts := *l.options
+	defaultLogger.Store(&logger{
+		options: &opts,
+		keyvals: l.keyvals,
+		groups:  l.groups,
+		flushed: true,
+	})
+}
+
+// SetStrict enables or disables the reporting of logging calls made with
+// contexts that were not initialized with Context or WithContext. When
+// enabled, the location of the first such call made from each call site is
+// written to stderr. The calls are logged with the default logger if one is
+// set (see SetDefault) and dropped otherwise, as when strict mode is disabled.
+// Changing the mode forgets the call sites already reported.
+func SetStrict(enabled bool) {
+	if strict.Swap(enabled) != enabled {
+		reportedCallers.Clear()
+	}
+}
+
+// UninitializedCalls returns the total number of logging calls made by the
+// process with contexts that were not initialized with Context or
+// WithContext, whether or not strict mode is enabled.
+func UninitializedCalls() uint64 {
+	return uninitializedCalls.Load()
+}
+
+// loggerFrom returns the logger of the given context or the default logger if
+// the context is not initialized. It returns nil if there is neither.
+func loggerFrom(ctx context.Context) *logger {
+	if l, ok := ctx.Value(ctxLogger).(*logger); ok {
+		return l
+	}
+	return defaultLogger.Load()
+}
+
+// uninitialized records a logging call made with an uninitialized context and
+// reports its location in strict mode. It returns the default logger, nil if
+// there is none.
+func uninitialized() *logger {
+	uninitializedCalls.Add(1)
+	if strict.Load() {
+		if file, line, ok := caller(); ok {
+			loc := fmt.Sprintf("%s:%d", file, line)
+			if _, reported := reportedCallers.LoadOrStore(loc, struct{}{}); !reported {
+				fmt.Fprintf(strictOutput, "clue/log: logging with a context not initialized with log.Context at %s\n", loc)
+			}
+		}
+	}
+	return defaultLogger.Load()
+}
+
+// caller returns the location of the first caller outside of this package.
+func caller() (string, int, bool) {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "goa.design/clue/log.") || strings.HasSuffix(f.File, "_test.go") {
+			return f.File, f.Line, f.Function != ""
+		}
+		if !more {
+			return "", 0, false
+		}
+	}
+}
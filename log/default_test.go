@@ -0,0 +1,78 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDefault(t *testing.T) {
+	t.Cleanup(func() { SetDefault(context.Background()) })
+	bg := context.Background()
+	var buf bytes.Buffer
+	ctx := Context(bg, WithOutputs(Output{Writer: &buf, Format: FormatText}), WithErrorChain())
+	ctx = With(ctx, KV{"svc", "worker"})
+
+	Print(bg, KV{"msg", "dropped"})
+	assert.Empty(t, buf.String())
+	assert.False(t, DebugEnabled(bg))
+
+	SetDefault(ctx)
+	Info(bg, KV{"msg", "not buffered"})
+	Errorf(bg, fmt.Errorf("wrap: %w", errors.New("boom")), "failed")
+	Print(With(bg, KV{"k", "v"}), KV{"msg", "derived"})
+	Debugf(bg, "debug disabled")
+
+	want := `time=2022-02-22T17:00:00Z level=info svc=worker msg="not buffered"
+time=2022-02-22T17:00:00Z level=error svc=worker err="wrap: boom" err.chain=[wrap: boom boom] msg=failed
+time=2022-02-22T17:00:00Z level=info svc=worker k=v msg=derived
+`
+	assert.Equal(t, want, buf.String())
+
+	buf.Reset()
+	Info(ctx, KV{"msg", "buffered"})
+	assert.Empty(t, buf.String(), "the default logger must not affect the buffering of the log context")
+	Context(ctx, WithDebug())
+	assert.False(t, DebugEnabled(bg), "options set after SetDefault must not apply to the default logger")
+
+	SetDefault(bg)
+	buf.Reset()
+	Print(bg, KV{"msg", "dropped"})
+	assert.Empty(t, buf.String())
+}
+
+func TestStrict(t *testing.T) {
+	var out bytes.Buffer
+	strictOutput = &out
+	t.Cleanup(func() {
+		SetStrict(false)
+		strictOutput = os.Stderr
+	})
+	bg := context.Background()
+	before := UninitializedCalls()
+
+	Print(bg, KV{"msg", "not reported"})
+	assert.Empty(t, out.String())
+
+	SetStrict(true)
+	for range 2 {
+		Infof(bg, "reported once")
+	}
+	Error(bg, errors.New("boom"))
+	Info(Context(bg), KV{"msg", "initialized"})
+
+	assert.Equal(t, uint64(4), UninitializedCalls()-before)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	for _, l := range lines {
+		assert.Regexp(t, `^clue/log: logging with a context not initialized with log.Context at .*/default_test.go:\d+$`, l)
+	}
+	assert.NotEqual(t, lines[0], lines[1])
+}
@@ -0,0 +1,172 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+
+	goa "goa.design/goa/v3/pkg"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	// Register the standard error detail types so that they can be decoded.
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// maxStackDepth is the maximum number of frames captured by WithErrorStack.
+const maxStackDepth = 32
+
+// WithErrorChain adds the messages of the errors wrapped by errors logged with
+// Error under ErrorChainKey. The chain is computed using errors.Unwrap and
+// follows all the errors joined with errors.Join. The first element is the
+// message of the logged error.
+func WithErrorChain() LogOption {
+	return func(o *options) {
+		o.errorChain = true
+	}
+}
+
+// WithErrorStack adds the stack trace of the calls to Error under
+// ErrorStackKey. Each element of the stack trace is formatted as
+// "function (file:line)".
+func WithErrorStack() LogOption {
+	return func(o *options) {
+		o.errorStack = true
+	}
+}
+
+// WithGoaErrorFields adds the fields of the Goa service errors logged with
+// Error as separate keys: GoaErrorNameKey, GoaErrorIDKey, GoaErrorFaultKey,
+// GoaErrorTemporaryKey and GoaErrorTimeoutKey. The service error may be
+// wrapped.
+func WithGoaErrorFields() LogOption {
+	return func(o *options) {
+		o.goaErrorFields = true
+	}
+}
+
+// WithGRPCErrorDetails adds the code and details of the gRPC status errors
+// logged with Error under ErrorGRPCCodeKey and ErrorGRPCDetailsKey. Details
+// are rendered using the protobuf JSON encoding.
+func WithGRPCErrorDetails() LogOption {
+	return func(o *options) {
+		o.grpcErrorDetails = true
+	}
+}
+
+// logError implements Error. It must be called directly by the exported
+// functions so that the stack trace starts at their caller.
+func logError(ctx context.Context, err error, keyvals []Fielder) {
+	FlushAndDisableBuffering(ctx)
+	if err != nil {
+		kvs := make([]Fielder, 0, len(keyvals)+8)
+		kvs = append(kvs, KV{ErrorMessageKey, err.Error()})
+		kvs = append(kvs, errorKeyVals(ctx, err)...)
+		kvs = append(kvs, keyvals...)
+		keyvals = kvs
+	}
+	log(ctx, SeverityError, true, keyvals)
+	recordSpanError(ctx, err)
+}
+
+// errorKeyVals returns the key/value pairs describing err enabled in the log
+// context.
+func errorKeyVals(ctx context.Context, err error) []Fielder {
+	l := loggerFrom(ctx)
+	if l == nil {
+		return nil
+	}
+	owner := l.owner()
+	owner.lock.Lock()
+	o := *l.options
+	owner.lock.Unlock()
+
+	var kvs []Fielder
+	if o.errorChain {
+		if chain := errorChain(err); len(chain) > 1 {
+			kvs = append(kvs, KV{ErrorChainKey, chain})
+		}
+	}
+	if o.errorStack {
+		kvs = append(kvs, KV{ErrorStackKey, callerStack(5)})
+	}
+	if o.goaErrorFields {
+		var se *goa.ServiceError
+		if errors.As(err, &se) {
+			kvs = append(kvs,
+				KV{GoaErrorNameKey, se.Name},
+				KV{GoaErrorIDKey, se.ID},
+				KV{GoaErrorFaultKey, se.Fault},
+				KV{GoaErrorTemporaryKey, se.Temporary},
+				KV{GoaErrorTimeoutKey, se.Timeout})
+		}
+	}
+	if o.grpcErrorDetails {
+		if st, ok := status.FromError(err); ok {
+			kvs = append(kvs, KV{ErrorGRPCCodeKey, st.Code()})
+			if details := grpcErrorDetails(st); len(details) > 0 {
+				kvs = append(kvs, KV{ErrorGRPCDetailsKey, details})
+			}
+		}
+	}
+	return kvs
+}
+
+// errorChain returns the messages of err and of the errors it wraps.
+func errorChain(err error) []string {
+	var chain []string
+	var walk func(error)
+	walk = func(err error) {
+		for err != nil {
+			chain = append(chain, err.Error())
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, e := range joined.Unwrap() {
+					walk(e)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// callerStack returns the stack trace of the caller skipping the given number
+// of frames.
+func callerStack(skip int) []string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	stack := make([]string, 0, n)
+	for {
+		f, more := frames.Next()
+		stack = append(stack, f.Function+" ("+f.File+":"+strconv.Itoa(f.Line)+")")
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// grpcErrorDetails returns the JSON representation of the details of st.
+func grpcErrorDetails(st *status.Status) []string {
+	details := st.Details()
+	res := make([]string, 0, len(details))
+	for _, d := range details {
+		switch d := d.(type) {
+		case proto.Message:
+			b, err := protojson.Marshal(d)
+			if err != nil {
+				res = append(res, err.Error())
+				continue
+			}
+			res = append(res, string(b))
+		case error:
+			res = append(res, d.Error())
+		}
+	}
+	return res
+}
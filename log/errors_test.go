@@ -0,0 +1,131 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	goa "goa.design/goa/v3/pkg"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithErrorChain(t *testing.T) {
+	root := errors.New("root")
+	other := errors.New("other")
+	cases := []struct {
+		name string
+		err  error
+		want []KV
+	}{
+		{"single", root, []KV{{ErrorMessageKey, "root"}}},
+		{"wrapped", fmt.Errorf("wrap: %w", root), []KV{
+			{ErrorMessageKey, "wrap: root"},
+			{ErrorChainKey, []string{"wrap: root", "root"}}}},
+		{"joined", fmt.Errorf("wrap: %w", errors.Join(root, fmt.Errorf("b: %w", other))), []KV{
+			{ErrorMessageKey, "wrap: root\nb: other"},
+			{ErrorChainKey, []string{"wrap: root\nb: other", "root\nb: other", "root", "b: other", "other"}}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var entry *Entry
+			ctx := Context(context.Background(),
+				WithOutputs(Output{Handle: func(e *Entry) error { entry = e; return nil }}),
+				WithErrorChain())
+			Error(ctx, c.err)
+			require.NotNil(t, entry)
+			assert.Equal(t, kvList(c.want), entry.KeyVals)
+		})
+	}
+}
+
+func TestWithErrorStack(t *testing.T) {
+	osExit = func(int) {}
+	defer func() { osExit = os.Exit }()
+	var entries []*Entry
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Handle: func(e *Entry) error { entries = append(entries, e); return nil }}),
+		WithErrorStack())
+	err := errors.New("boom")
+	Error(ctx, err)
+	Errorf(ctx, err, "failed")
+	Fatal(ctx, err)
+	Fatalf(ctx, err, "failed")
+	Error(ctx, nil)
+
+	require.Len(t, entries, 5)
+	for i, e := range entries[:4] {
+		require.Len(t, e.KeyVals, 2+min(i%2, 1), "entry %d", i)
+		kv := e.KeyVals[1]
+		assert.Equal(t, ErrorStackKey, kv.K)
+		stack, ok := kv.V.([]string)
+		require.True(t, ok)
+		require.NotEmpty(t, stack)
+		assert.True(t, strings.HasPrefix(stack[0], "goa.design/clue/log.TestWithErrorStack ("), "entry %d: stack must start at caller, got %q", i, stack[0])
+		assert.Contains(t, stack[0], "errors_test.go:")
+	}
+	assert.Empty(t, entries[4].KeyVals, "nil errors must not log a stack")
+}
+
+func TestWithGoaErrorFields(t *testing.T) {
+	var entry *Entry
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Handle: func(e *Entry) error { entry = e; return nil }}),
+		WithGoaErrorFields())
+	se := &goa.ServiceError{Name: "not_found", ID: "abc", Message: "missing", Temporary: true}
+	Error(ctx, fmt.Errorf("load: %w", se), KV{MessageKey, "failed"})
+
+	require.NotNil(t, entry)
+	assert.Equal(t, kvList{
+		{ErrorMessageKey, "load: missing"},
+		{GoaErrorNameKey, "not_found"},
+		{GoaErrorIDKey, "abc"},
+		{GoaErrorFaultKey, false},
+		{GoaErrorTemporaryKey, true},
+		{GoaErrorTimeoutKey, false},
+		{MessageKey, "failed"},
+	}, entry.KeyVals)
+
+	Error(ctx, errors.New("plain"))
+	assert.Equal(t, kvList{{ErrorMessageKey, "plain"}}, entry.KeyVals)
+}
+
+func TestWithGRPCErrorDetails(t *testing.T) {
+	var entry *Entry
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Handle: func(e *Entry) error { entry = e; return nil }}),
+		WithGRPCErrorDetails())
+	st, err := status.New(codes.InvalidArgument, "bad request").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "required"}},
+	})
+	require.NoError(t, err)
+	Error(ctx, st.Err())
+
+	require.NotNil(t, entry)
+	require.Len(t, entry.KeyVals, 3)
+	assert.Equal(t, KV{ErrorGRPCCodeKey, codes.InvalidArgument}, entry.KeyVals[1])
+	assert.Equal(t, ErrorGRPCDetailsKey, entry.KeyVals[2].K)
+	details, ok := entry.KeyVals[2].V.([]string)
+	require.True(t, ok)
+	require.Len(t, details, 1)
+	assert.JSONEq(t, `{"fieldViolations":[{"field":"name","description":"required"}]}`, details[0])
+
+	Error(ctx, status.Error(codes.NotFound, "missing"))
+	assert.Equal(t, kvList{{ErrorMessageKey, "rpc error: code = NotFound desc = missing"}, {ErrorGRPCCodeKey, codes.NotFound}}, entry.KeyVals)
+
+	Error(ctx, errors.New("plain"))
+	assert.Equal(t, kvList{{ErrorMessageKey, "plain"}}, entry.KeyVals)
+}
+
+func TestErrorDetailsDisabled(t *testing.T) {
+	var entry *Entry
+	ctx := Context(context.Background(), WithOutputs(Output{Handle: func(e *Entry) error { entry = e; return nil }}))
+	Error(ctx, fmt.Errorf("wrap: %w", &goa.ServiceError{Name: "n", Message: "m"}))
+	assert.Equal(t, kvList{{ErrorMessageKey, "wrap: m"}}, entry.KeyVals)
+}
@@ -1,8 +1,17 @@
 package log
 
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unsafe"
+)
+
 type (
 	// KV represents a key/value pair. Values must be strings, numbers,
-	// booleans, nil or a slice of these types.
+	// booleans, nil or a slice of these types. A []KV value represents a
+	// group of key/value pairs, see Group.
 	KV struct {
 		K string
 		V any
@@ -17,9 +26,248 @@ type (
 	// non-deterministic order of the fields
 	Fields map[string]any
 
+	// Field is a key/value pair created with String, Int64, Dur, Time, Err
+	// or Stringer. The value is stored without being boxed in an interface
+	// so that logging a field allocates the field only, and a Field is small
+	// enough to use the same size class as a boxed KV. The key/value pairs of
+	// the entries logged with a field have the Field as value: custom
+	// formatters and handlers must use Value to retrieve the underlying
+	// value, see Entry.
+	Field struct {
+		K string
+		// num holds the value of integer and duration fields, the Unix
+		// nanoseconds of time fields and the length of string values.
+		num uint64
+		// ptr points to the bytes of string values, to one of the
+		// fieldTags for the other kinds or to the boxed value of fieldAny
+		// fields.
+		ptr unsafe.Pointer
+	}
+
+	// fieldKind identifies the slot holding the value of a field.
+	fieldKind uint8
+
 	kvList []KV
 )
 
+const (
+	// fieldString values are stored in ptr and num.
+	fieldString fieldKind = iota
+	// fieldInt64 values are stored in num.
+	fieldInt64
+	// fieldDuration values are stored in num as nanoseconds.
+	fieldDuration
+	// fieldTimeUTC values are stored in num as Unix nanoseconds.
+	fieldTimeUTC
+	// fieldTimeLocal values are stored in num as Unix nanoseconds.
+	fieldTimeLocal
+	// fieldNil fields have a nil value.
+	fieldNil
+	// fieldAny values are boxed, ptr points to the box.
+	fieldAny
+)
+
+// fieldAnyLen is the value of num for fieldAny fields, it is not a valid
+// string length.
+const fieldAnyLen = math.MaxUint64
+
+// fieldTags are pointed to by the ptr of the fields whose value is stored in
+// num, the index of the tag is the field kind.
+var fieldTags [fieldAny]byte
+
+// minNanoTime and maxNanoTime are the bounds of the times that can be stored
+// as Unix nanoseconds.
+var (
+	minNanoTime = time.Unix(0, math.MinInt64)
+	maxNanoTime = time.Unix(0, math.MaxInt64)
+)
+
+// String returns a field with a string value.
+func String(k, v string) Field {
+	return Field{K: k, num: uint64(len(v)), ptr: unsafe.Pointer(unsafe.StringData(v))}
+}
+
+// Int64 returns a field with an integer value.
+func Int64(k string, v int64) Field {
+	return tagged(k, fieldInt64, uint64(v))
+}
+
+// Dur returns a field with a duration value. Durations are rendered using
+// time.Duration.String.
+func Dur(k string, v time.Duration) Field {
+	return tagged(k, fieldDuration, uint64(v))
+}
+
+// Time returns a field with a time value. Times are rendered using the
+// time.RFC3339Nano layout.
+func Time(k string, v time.Time) Field {
+	switch {
+	case v.Before(minNanoTime) || v.After(maxNanoTime):
+		return boxed(k, v)
+	case v.Location() == time.UTC:
+		return tagged(k, fieldTimeUTC, uint64(v.UnixNano()))
+	case v.Location() == time.Local:
+		return tagged(k, fieldTimeLocal, uint64(v.UnixNano()))
+	default:
+		return boxed(k, v)
+	}
+}
+
+// Err returns a field with key ErrorMessageKey and the error message as value,
+// nil if err is nil.
+func Err(err error) Field {
+	if err == nil {
+		return tagged(ErrorMessageKey, fieldNil, 0)
+	}
+	return String(ErrorMessageKey, err.Error())
+}
+
+// Stringer returns a field whose value is the result of calling v.String(),
+// nil if v is nil. The value is computed when Stringer is called so that later
+// changes to v do not affect buffered entries.
+func Stringer(k string, v fmt.Stringer) Field {
+	if v == nil {
+		return tagged(k, fieldNil, 0)
+	}
+	return String(k, v.String())
+}
+
+// tagged returns a field of the given kind whose value is stored in num.
+func tagged(k string, kind fieldKind, num uint64) Field {
+	return Field{K: k, num: num, ptr: unsafe.Pointer(&fieldTags[kind])}
+}
+
+// boxed returns a field of kind fieldAny.
+func boxed(k string, v any) Field {
+	return Field{K: k, num: fieldAnyLen, ptr: unsafe.Pointer(&v)}
+}
+
+// Value returns the value of the field.
+func (f Field) Value() any {
+	switch f.kind() {
+	case fieldString:
+		return f.str()
+	case fieldInt64:
+		return int64(f.num)
+	case fieldDuration:
+		return time.Duration(f.num)
+	case fieldTimeUTC, fieldTimeLocal:
+		return f.time()
+	case fieldAny:
+		return f.any()
+	default:
+		return nil
+	}
+}
+
+// String returns the text representation of the field value.
+func (f Field) String() string {
+	return string(f.appendPlain(nil))
+}
+
+// MarshalJSON returns the JSON representation of the field value.
+func (f Field) MarshalJSON() ([]byte, error) {
+	return f.appendJSON(nil), nil
+}
+
+// LogFields returns a key/value pair whose value is the field.
+func (f Field) LogFields() []KV {
+	return []KV{{K: f.K, V: f}}
+}
+
+// kind returns the kind of the field.
+func (f Field) kind() fieldKind {
+	for k := range fieldTags {
+		if f.ptr == unsafe.Pointer(&fieldTags[k]) {
+			return fieldKind(k)
+		}
+	}
+	if f.num == fieldAnyLen {
+		return fieldAny
+	}
+	return fieldString
+}
+
+// str returns the value of a field of kind fieldString.
+func (f Field) str() string {
+	return unsafe.String((*byte)(f.ptr), int(f.num))
+}
+
+// time returns the value of a field of kind fieldTimeUTC or fieldTimeLocal.
+func (f Field) time() time.Time {
+	t := time.Unix(0, int64(f.num))
+	if f.ptr == unsafe.Pointer(&fieldTags[fieldTimeUTC]) {
+		return t.UTC()
+	}
+	return t
+}
+
+// any returns the value of a field of kind fieldAny.
+func (f Field) any() any {
+	return *(*any)(f.ptr)
+}
+
+// appendPlain appends the unquoted text representation of the field value to
+// b.
+func (f Field) appendPlain(b []byte) []byte {
+	switch f.kind() {
+	case fieldString:
+		return append(b, f.str()...)
+	case fieldInt64:
+		return strconv.AppendInt(b, int64(f.num), 10)
+	case fieldDuration:
+		return append(b, time.Duration(f.num).String()...)
+	case fieldTimeUTC, fieldTimeLocal:
+		return f.time().AppendFormat(b, time.RFC3339Nano)
+	case fieldAny:
+		return appendPlainValue(b, f.any())
+	default:
+		return appendPlainValue(b, nil)
+	}
+}
+
+// appendJSON appends the JSON representation of the field value to b.
+func (f Field) appendJSON(b []byte) []byte {
+	switch f.kind() {
+	case fieldString:
+		return appendJSONString(b, f.str())
+	case fieldInt64:
+		return strconv.AppendInt(b, int64(f.num), 10)
+	case fieldDuration:
+		return appendJSONString(b, time.Duration(f.num).String())
+	case fieldTimeUTC, fieldTimeLocal:
+		b = append(b, '"')
+		b = f.time().AppendFormat(b, time.RFC3339Nano)
+		return append(b, '"')
+	case fieldAny:
+		return appendJSONValue(b, f.any())
+	default:
+		return appendJSONValue(b, nil)
+	}
+}
+
+// plainValue returns the value of v if v is a Field, v otherwise.
+func plainValue(v any) any {
+	if f, ok := v.(Field); ok {
+		return f.Value()
+	}
+	return v
+}
+
+// stringValue returns v as a string if v is a string or a Field with a string
+// value.
+func stringValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case Field:
+		if v.kind() == fieldString {
+			return v.str(), true
+		}
+	}
+	return "", false
+}
+
 func (kv KV) LogFields() []KV {
 	return []KV{kv}
 }
@@ -36,9 +284,10 @@ func (kvs kvList) merge(fielders []Fielder) kvList {
 	totalLen := len(kvs)
 	cachedFields := make([][]KV, len(fielders))
 	for i, fielder := range fielders {
-		if _, ok := fielder.(KV); ok {
+		switch fielder.(type) {
+		case KV, Field:
 			totalLen++
-		} else {
+		default:
 			fields := fielder.LogFields()
 			cachedFields[i] = fields
 			totalLen += len(fields)
@@ -47,9 +296,13 @@ func (kvs kvList) merge(fielders []Fielder) kvList {
 	result := make(kvList, len(kvs), totalLen)
 	copy(result, kvs)
 	for i, fielder := range fielders {
-		if kv, ok := fielder.(KV); ok {
-			result = append(result, kv)
-		} else {
+		switch f := fielder.(type) {
+		case KV:
+			result = append(result, f)
+		case Field:
+			// fielder holds the boxed field, storing it does not allocate.
+			result = append(result, KV{K: f.K, V: fielder})
+		default:
 			result = append(result, cachedFields[i]...)
 		}
 	}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestTypedFields(t *testing.T) {
+	now := time.Date(2022, time.January, 9, 20, 29, 45, 123, time.UTC)
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	old := time.Date(1492, time.October, 12, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		field Field
+		key   string
+		value any
+		text  string
+		json  string
+	}{
+		{"string", String("k", "v"), "k", "v", "v", `"v"`},
+		{"int64", Int64("k", 42), "k", int64(42), "42", "42"},
+		{"dur", Dur("k", time.Second), "k", time.Second, "1s", `"1s"`},
+		{"time", Time("k", now), "k", now, "2022-01-09T20:29:45.000000123Z", `"2022-01-09T20:29:45.000000123Z"`},
+		{"time location", Time("k", now.In(paris)), "k", now.In(paris), "2022-01-09T21:29:45.000000123+01:00", `"2022-01-09T21:29:45.000000123+01:00"`},
+		{"time out of range", Time("k", old), "k", old, "1492-10-12T00:00:00Z", `"1492-10-12T00:00:00Z"`},
+		{"err", Err(errors.New("boom")), ErrorMessageKey, "boom", "boom", `"boom"`},
+		{"nil err", Err(nil), ErrorMessageKey, nil, "<nil>", "null"},
+		{"stringer", Stringer("k", codes.NotFound), "k", "NotFound", "NotFound", `"NotFound"`},
+		{"nil stringer", Stringer("k", nil), "k", nil, "<nil>", "null"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.key, c.field.K)
+			assert.Equal(t, c.value, c.field.Value())
+			assert.Equal(t, c.text, c.field.String())
+			js, err := json.Marshal(c.field)
+			require.NoError(t, err)
+			assert.Equal(t, c.json, string(js))
+			assert.Equal(t, []KV{{c.key, c.field}}, c.field.LogFields())
+		})
+	}
+	assert.Equal(t, now.Local(), Time("k", now.Local()).Value(), "local times must keep their location")
+	assert.Equal(t, "", Field{}.Value(), "the zero field must have an empty string value")
+}
+
+func TestTypedFieldsEntry(t *testing.T) {
+	var entries []*Entry
+	ctx := Context(context.Background(), WithOutputs(Output{Handle: func(e *Entry) error {
+		entries = append(entries, e)
+		return nil
+	}}))
+	f := String("k", "v")
+	Print(ctx, f, Int64("n", 1))
+	Print(With(ctx, f), KV{"a", 1})
+
+	require.Len(t, entries, 2)
+	require.Len(t, entries[0].KeyVals, 2)
+	assert.Equal(t, KV{"k", f}, entries[0].KeyVals[0])
+	assert.Equal(t, int64(1), plainValue(entries[0].KeyVals[1].V))
+	assert.Equal(t, f, entries[1].KeyVals[0].V, "the field must be stored as is")
+	assert.Equal(t, 1, plainValue(entries[1].KeyVals[1].V))
+}
+
+func TestTypedFieldsAllocs(t *testing.T) {
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: nopWriter{}, Format: FormatJSON}))
+	FlushAndDisableBuffering(ctx)
+	assertTypedAllocs(t, ctx)
+}
+
+var (
+	benchDur     = 42 * time.Millisecond
+	benchStarted = time.Date(2022, time.January, 9, 20, 29, 45, 0, time.UTC)
+)
+
+// printKV logs a typical entry using KV.
+func printKV(ctx context.Context) {
+	Print(ctx,
+		KV{MessageKey, "request handled"},
+		KV{HTTPStatusKey, 200},
+		KV{HTTPBytesKey, 4096},
+		KV{"dur", benchDur},
+		KV{"started", benchStarted})
+}
+
+// printTyped logs the same entry as printKV using typed fields.
+func printTyped(ctx context.Context) {
+	Print(ctx,
+		String(MessageKey, "request handled"),
+		Int64(HTTPStatusKey, 200),
+		Int64(HTTPBytesKey, 4096),
+		Dur("dur", benchDur),
+		Time("started", benchStarted))
+}
+
+// assertTypedAllocs asserts that logging with typed fields allocates fewer
+// objects and bytes than logging with KV.
+func assertTypedAllocs(tb testing.TB, ctx context.Context) {
+	kvAllocs, kvBytes := allocsPerRun(100, func() { printKV(ctx) })
+	typedAllocs, typedBytes := allocsPerRun(100, func() { printTyped(ctx) })
+	assert.Less(tb, typedAllocs, kvAllocs, "allocs per op")
+	assert.Less(tb, typedBytes, kvBytes, "bytes per op")
+}
+
+// allocsPerRun returns the average number of allocations and of allocated
+// bytes of a call to fn.
+func allocsPerRun(runs int, fn func()) (allocs, bytes uint64) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	fn() // warm up
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for range runs {
+		fn()
+	}
+	runtime.ReadMemStats(&after)
+	return (after.Mallocs - before.Mallocs) / uint64(runs), (after.TotalAlloc - before.TotalAlloc) / uint64(runs)
+}
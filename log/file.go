@@ -0,0 +1,453 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// FileOption is a function that applies a configuration option to a
+	// file writer.
+	FileOption func(*fileOptions)
+
+	// FileWriter is an io.Writer that writes to a file and rotates it based on
+	// size and age. Rotated files (backups) are renamed using the time of the
+	// rotation, optionally compressed with gzip and deleted once they exceed
+	// the configured retention. FileWriter can also reopen the file when the
+	// process receives SIGHUP so that it can be used with external tools that
+	// move log files, see WithFileReopenOnSIGHUP.
+	//
+	// FileWriter is safe for concurrent use and may be shared by multiple
+	// outputs.
+	FileWriter struct {
+		path    string
+		options *fileOptions
+
+		lock     sync.Mutex
+		file     *os.File
+		size     int64
+		openedAt time.Time
+		closed   bool
+
+		mill    chan struct{}
+		signals chan os.Signal
+		done    chan struct{}
+		wg      sync.WaitGroup
+	}
+
+	fileOptions struct {
+		maxSize    int64
+		interval   time.Duration
+		maxAge     time.Duration
+		maxBackups int
+		compress   bool
+		sighup     bool
+	}
+)
+
+// backupTimeFormat is the layout used to name rotated files.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// compressSuffix is appended to the name of compressed backups.
+const compressSuffix = ".gz"
+
+// NewFileWriter opens or creates the file at path for appending and returns a
+// writer that rotates it according to the given options. The parent directory
+// is created if it does not exist. Close must be called to release the file
+// and the background goroutines.
+//
+// Usage:
+//
+//	w, err := log.NewFileWriter("/var/log/svc/svc.log",
+//	    log.WithFileMaxSize(100<<20),
+//	    log.WithFileMaxBackups(10),
+//	    log.WithFileCompress())
+//	if err != nil {
+//	    return err
+//	}
+//	defer w.Close()
+//	ctx := log.Context(ctx, log.WithOutputs(log.Output{Writer: w, Format: log.FormatJSON}))
+func NewFileWriter(path string, opts ...FileOption) (*FileWriter, error) {
+	o := &fileOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	w := &FileWriter{
+		path:    path,
+		options: o,
+		mill:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.wg.Add(1)
+	go w.runMill()
+	if o.sighup {
+		w.notifySIGHUP()
+	}
+	return w, nil
+}
+
+// WithFileMaxSize sets the maximum size in bytes of the log file before it
+// gets rotated. A value of 0 (the default) disables size based rotation.
+func WithFileMaxSize(n int64) FileOption {
+	return func(o *fileOptions) {
+		o.maxSize = n
+	}
+}
+
+// WithFileRotationInterval sets the maximum duration a log file is written to
+// before it gets rotated. A value of 0 (the default) disables time based
+// rotation.
+func WithFileRotationInterval(d time.Duration) FileOption {
+	return func(o *fileOptions) {
+		o.interval = d
+	}
+}
+
+// WithFileMaxAge sets the maximum age of backups, older backups are deleted.
+// A value of 0 (the default) retains backups regardless of their age.
+func WithFileMaxAge(d time.Duration) FileOption {
+	return func(o *fileOptions) {
+		o.maxAge = d
+	}
+}
+
+// WithFileMaxBackups sets the maximum number of backups to retain, the oldest
+// backups are deleted first. A value of 0 (the default) retains all backups.
+func WithFileMaxBackups(n int) FileOption {
+	return func(o *fileOptions) {
+		o.maxBackups = n
+	}
+}
+
+// WithFileCompress compresses backups using gzip.
+func WithFileCompress() FileOption {
+	return func(o *fileOptions) {
+		o.compress = true
+	}
+}
+
+// WithFileReopenOnSIGHUP reopens the file when the process receives SIGHUP.
+// This option has no effect on platforms that do not support SIGHUP.
+func WithFileReopenOnSIGHUP() FileOption {
+	return func(o *fileOptions) {
+		o.sighup = true
+	}
+}
+
+// Write writes p to the file, rotating the file first if writing p would
+// exceed the maximum size or if the rotation interval has elapsed.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		// A previous rotation failed to open the new file, try again.
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it using the current time and
+// opens a new file.
+func (w *FileWriter) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes and reopens the file. It is called automatically when the
+// process receives SIGHUP if the writer was created with
+// WithFileReopenOnSIGHUP. The current file is kept open if the file cannot be
+// reopened.
+func (w *FileWriter) Reopen() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	old := w.file
+	if err := w.open(); err != nil {
+		return err
+	}
+	if old == nil {
+		return nil
+	}
+	return old.Close()
+}
+
+// Close closes the file and waits for pending compressions and deletions to
+// complete.
+func (w *FileWriter) Close() error {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+	}
+	if w.signals != nil {
+		w.stopSIGHUP()
+	}
+	close(w.done)
+	w.lock.Unlock()
+	w.wg.Wait()
+	return err
+}
+
+// shouldRotate returns true if the file must be rotated before writing n
+// bytes.
+func (w *FileWriter) shouldRotate(n int64) bool {
+	if w.options.maxSize > 0 && w.size > 0 && w.size+n > w.options.maxSize {
+		return true
+	}
+	return w.options.interval > 0 && timeNow().Sub(w.openedAt) >= w.options.interval
+}
+
+// open opens or creates the file for appending. w.file is left unchanged if
+// the file cannot be opened.
+func (w *FileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close() // nolint: errcheck
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.openedAt = timeNow()
+	return nil
+}
+
+// rotate renames the current file and opens a new one. The rename is atomic
+// so no write is lost. The file is reopened if it cannot be renamed. If no
+// file can be opened w.file is nil and the next write tries again. The lock
+// must be held.
+func (w *FileWriter) rotate() error {
+	if w.file != nil {
+		err := w.file.Close()
+		w.file = nil
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, w.backupName()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Join(err, w.open())
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// backupName returns a unique name for a backup of the file.
+func (w *FileWriter) backupName() string {
+	prefix, ext := w.backupPrefixExt()
+	base := prefix + timeNow().UTC().Format(backupTimeFormat)
+	name := base + ext
+	for i := 1; fileExists(name) || fileExists(name+compressSuffix); i++ {
+		name = base + "-" + strconv.Itoa(i) + ext
+	}
+	return name
+}
+
+// backupPrefixExt returns the prefix and extension of backup file names.
+func (w *FileWriter) backupPrefixExt() (string, string) {
+	ext := filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "-", ext
+}
+
+// runMill compresses and deletes backups after each rotation.
+func (w *FileWriter) runMill() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.mill:
+			w.millBackups() // nolint: errcheck
+		case <-w.done:
+			select {
+			case <-w.mill:
+				w.millBackups() // nolint: errcheck
+			default:
+			}
+			return
+		}
+	}
+}
+
+// backupFile describes a rotated file.
+type backupFile struct {
+	path    string
+	modTime time.Time
+}
+
+// millBackups compresses the uncompressed backups if needed and deletes the
+// backups that exceed the retention settings.
+func (w *FileWriter) millBackups() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	var keep []backupFile
+	cutoff := timeNow().Add(-w.options.maxAge)
+	for i, b := range backups {
+		if (w.options.maxBackups > 0 && i >= w.options.maxBackups) ||
+			(w.options.maxAge > 0 && b.modTime.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		keep = append(keep, b)
+	}
+	if w.options.compress {
+		for _, b := range keep {
+			if strings.HasSuffix(b.path, compressSuffix) {
+				continue
+			}
+			if err := compressFile(b.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// backups returns the backups of the file sorted from newest to oldest.
+func (w *FileWriter) backups() ([]backupFile, error) {
+	prefix, ext := w.backupPrefixExt()
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(prefix)
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !isBackupName(name, base, ext) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{filepath.Join(filepath.Dir(w.path), name), info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].path > backups[j].path
+		}
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	return backups, nil
+}
+
+// isBackupName returns true if name is the name of a backup created by
+// backupName for a file with the given prefix and extension, optionally
+// compressed.
+func isBackupName(name, prefix, ext string) bool {
+	name = strings.TrimSuffix(name, compressSuffix)
+	ts, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
+	}
+	if ts, ok = strings.CutSuffix(ts, ext); !ok || len(ts) < len(backupTimeFormat) {
+		return false
+	}
+	if _, err := time.Parse(backupTimeFormat, ts[:len(backupTimeFormat)]); err != nil {
+		return false
+	}
+	n, ok := strings.CutPrefix(ts[len(backupTimeFormat):], "-")
+	if !ok {
+		return ts == ts[:len(backupTimeFormat)]
+	}
+	_, err := strconv.ParseUint(n, 10, 64)
+	return err == nil
+}
+
+// compressFile compresses the file at path using gzip and deletes it. The
+// compressed file is written to a temporary file first and renamed once
+// complete.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close() // nolint: errcheck
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp := path + compressSuffix + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()    // nolint: errcheck
+		os.Remove(tmp) // nolint: errcheck
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()    // nolint: errcheck
+		os.Remove(tmp) // nolint: errcheck
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp) // nolint: errcheck
+		return err
+	}
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path+compressSuffix); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// fileExists returns true if a file exists at path.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
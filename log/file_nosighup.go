@@ -0,0 +1,9 @@
+//go:build !unix
+
+package log
+
+// notifySIGHUP does nothing on platforms that do not support SIGHUP.
+func (w *FileWriter) notifySIGHUP() {}
+
+// stopSIGHUP does nothing on platforms that do not support SIGHUP.
+func (w *FileWriter) stopSIGHUP() {}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifySIGHUP starts reopening the file when the process receives SIGHUP.
+func (w *FileWriter) notifySIGHUP() {
+	w.signals = make(chan os.Signal, 1)
+	signal.Notify(w.signals, syscall.SIGHUP)
+	w.wg.Add(1)
+	go w.handleSignals()
+}
+
+// stopSIGHUP stops relaying SIGHUP to the writer.
+func (w *FileWriter) stopSIGHUP() {
+	signal.Stop(w.signals)
+}
+
+// handleSignals reopens the file when the process receives SIGHUP.
+func (w *FileWriter) handleSignals() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.signals:
+			w.Reopen() // nolint: errcheck
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWriterReopenOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "svc.log")
+	w, err := NewFileWriter(path, WithFileReopenOnSIGHUP())
+	require.NoError(t, err)
+	defer w.Close() // nolint: errcheck
+	_, err = w.Write([]byte("before\n"))
+	require.NoError(t, err)
+
+	moved := filepath.Join(dir, "moved.log")
+	require.NoError(t, os.Rename(path, moved))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	require.Eventually(t, func() bool { return fileExists(path) }, time.Second, time.Millisecond)
+
+	_, err = w.Write([]byte("after\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", readFile(t, moved))
+	assert.Equal(t, "after\n", readFile(t, path))
+}
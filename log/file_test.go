@@ -0,0 +1,245 @@
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "svc.log")
+	w, err := NewFileWriter(path)
+	require.NoError(t, err)
+
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: w, Format: FormatText}))
+	Printf(ctx, "hello")
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "time=2022-02-22T17:00:00Z level=info msg=hello\n", readFile(t, path))
+	_, err = w.Write([]byte("closed"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.NoError(t, w.Close(), "Close must be idempotent")
+}
+
+func TestFileWriterAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svc.log")
+	require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0644))
+	w, err := NewFileWriter(path, WithFileMaxSize(12))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "new\n", readFile(t, path), "existing size must count towards max size")
+	assert.Equal(t, []string{"existing\n"}, backupContents(t, path))
+}
+
+func TestFileWriterMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svc.log")
+	w, err := NewFileWriter(path, WithFileMaxSize(10))
+	require.NoError(t, err)
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "line 3\n", readFile(t, path))
+	assert.ElementsMatch(t, []string{"line 1\n", "line 2\n"}, backupContents(t, path))
+}
+
+func TestFileWriterRotationInterval(t *testing.T) {
+	restore := timeNow
+	defer func() { timeNow = restore }()
+	now := time.Date(2022, time.February, 22, 17, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	path := filepath.Join(t.TempDir(), "svc.log")
+	w, err := NewFileWriter(path, WithFileRotationInterval(time.Hour))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("first\n"))
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = w.Write([]byte("second\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "second\n", readFile(t, path))
+	assert.Equal(t, []string{"first\n"}, backupContents(t, path))
+}
+
+func TestFileWriterCompress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svc.log")
+	w, err := NewFileWriter(path, WithFileCompress())
+	require.NoError(t, err)
+	_, err = w.Write([]byte("compressed\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Rotate())
+	require.NoError(t, w.Close())
+
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "svc-*.log.gz"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	f, err := os.Open(matches[0])
+	require.NoError(t, err)
+	defer f.Close() // nolint: errcheck
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "compressed\n", string(b))
+	assert.Empty(t, backupContents(t, path), "uncompressed backup must be deleted")
+}
+
+func TestFileWriterRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "svc.log")
+	old := filepath.Join(dir, "svc-2000-01-01T00-00-00.000.log")
+	require.NoError(t, os.WriteFile(old, []byte("old\n"), 0644))
+	oldTime := timeNow().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(old, oldTime, oldTime))
+
+	w, err := NewFileWriter(path, WithFileMaxAge(24*time.Hour), WithFileMaxBackups(2))
+	require.NoError(t, err)
+	for _, line := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		require.NoError(t, w.Rotate())
+		time.Sleep(10 * time.Millisecond) // distinct modification times
+	}
+	require.NoError(t, w.Close())
+
+	assert.NoFileExists(t, old)
+	assert.ElementsMatch(t, []string{"3\n", "4\n"}, backupContents(t, path))
+}
+
+func TestFileWriterRetentionIgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	others := []string{"app-worker.log", "app-2000-01-01.log", "app-2000-01-01T00-00-00.000-x.log", "app-2000-01-01T00-00-00.000.log.tmp"}
+	for _, name := range others {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+
+	w, err := NewFileWriter(path, WithFileMaxBackups(1), WithFileCompress())
+	require.NoError(t, err)
+	for range 3 {
+		require.NoError(t, w.Rotate())
+	}
+	require.NoError(t, w.Close())
+
+	for _, name := range others {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	backups, err := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+}
+
+func TestIsBackupName(t *testing.T) {
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{"app-2022-02-22T17-00-00.000.log", true},
+		{"app-2022-02-22T17-00-00.000-3.log", true},
+		{"app-2022-02-22T17-00-00.000.log.gz", true},
+		{"app-2022-02-22T17-00-00.000-3.log.gz", true},
+		{"app.log", false},
+		{"app-worker.log", false},
+		{"app-2022-02-22T17-00-00.000-.log", false},
+		{"app-2022-02-22T17-00-00.000-x.log", false},
+		{"app-2022-02-22T17-00-00.000x.log", false},
+		{"app-2022-02-22T17-00-00.000.txt", false},
+		{"other-2022-02-22T17-00-00.000.log", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, isBackupName(c.name, "app-", ".log"), c.name)
+	}
+}
+
+func TestFileWriterReopenError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svc.log")
+	w, err := NewFileWriter(path)
+	require.NoError(t, err)
+	defer w.Close() // nolint: errcheck
+
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.Mkdir(path, 0755))
+	assert.Error(t, w.Reopen())
+	_, err = w.Write([]byte("kept\n"))
+	assert.NoError(t, err, "the file must be kept open if it cannot be reopened")
+
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, w.Reopen())
+	_, err = w.Write([]byte("reopened\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "reopened\n", readFile(t, path))
+}
+
+func TestFileWriterRetriesOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svc.log")
+	w, err := NewFileWriter(path)
+	require.NoError(t, err)
+	defer w.Close() // nolint: errcheck
+
+	// Simulate a rotation that failed to open the new file.
+	require.NoError(t, w.file.Close())
+	w.file = nil
+
+	_, err = w.Write([]byte("line\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "line\n", readFile(t, path))
+}
+
+func TestFileWriterSharedOutputs(t *testing.T) {
+	// run with -race to detect data races
+	path := filepath.Join(t.TempDir(), "svc.log")
+	w, err := NewFileWriter(path, WithFileMaxSize(256))
+	require.NoError(t, err)
+	ctx := Context(context.Background(), WithOutputs(
+		Output{Writer: w, Format: FormatText},
+		Output{Writer: w, Format: FormatJSON},
+	))
+	done := make(chan struct{})
+	for range 10 {
+		go func() {
+			for range 10 {
+				Printf(ctx, "hello")
+			}
+			done <- struct{}{}
+		}()
+	}
+	for range 10 {
+		<-done
+	}
+	require.NoError(t, w.Close())
+	assert.NotEmpty(t, backupContents(t, path))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func backupContents(t *testing.T, path string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "svc-*.log"))
+	require.NoError(t, err)
+	sort.Strings(matches)
+	var contents []string
+	for _, m := range matches {
+		contents = append(contents, readFile(t, m))
+	}
+	return contents
+}
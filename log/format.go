@@ -38,14 +38,16 @@ var TimestampFormatLayout = time.RFC3339
 func FormatText(e *Entry) []byte {
 	b := make([]byte, 0, 256)
 
-	b = appendKeyValue(b, TimestampKey, e.Time.Format(TimestampFormatLayout))
+	var ts [64]byte
+	b = append(b, TimestampKey...)
+	b = append(b, '=')
+	b = appendEscapedString(b, e.Time.AppendFormat(ts[:0], TimestampFormatLayout))
 	b = append(b, ' ')
-	b = appendKeyValue(b, SeverityKey, e.Severity)
+	b = append(b, SeverityKey...)
+	b = append(b, '=')
+	b = append(b, e.Severity.String()...)
 
-	for _, kv := range e.KeyVals {
-		b = append(b, ' ')
-		b = appendKeyValue(b, kv.K, kv.V)
-	}
+	b = appendTextKeyVals(b, "", e.KeyVals)
 
 	b = append(b, '\n')
 	return b
@@ -71,9 +73,12 @@ func FormatJSON(e *Entry) []byte {
 	b := make([]byte, 0, 256)
 
 	b = append(b, '{')
-	b = appendJSONKeyValue(b, TimestampKey, e.Time.Format(TimestampFormatLayout))
+	var ts [64]byte
+	b = appendJSONKey(b, TimestampKey)
+	b = appendJSONString(b, e.Time.AppendFormat(ts[:0], TimestampFormatLayout))
 	b = append(b, ',')
-	b = appendJSONKeyValue(b, SeverityKey, e.Severity.String())
+	b = appendJSONKey(b, SeverityKey)
+	b = appendJSONString(b, e.Severity.String())
 
 	for _, kv := range e.KeyVals {
 		b = append(b, ',')
@@ -84,6 +89,22 @@ func FormatJSON(e *Entry) []byte {
 	return b
 }
 
+// appendTextKeyVals appends the given key/value pairs each preceded with a
+// space. Groups are flattened and their keys prefixed with the group name and
+// a dot.
+func appendTextKeyVals(b []byte, prefix string, kvs []KV) []byte {
+	for _, kv := range kvs {
+		if g, ok := kv.V.([]KV); ok {
+			b = appendTextKeyVals(b, prefix+kv.K+".", g)
+			continue
+		}
+		b = append(b, ' ')
+		b = append(b, prefix...)
+		b = appendKeyValue(b, kv.K, kv.V)
+	}
+	return b
+}
+
 func appendKeyValue(b []byte, key string, value any) []byte {
 	b = append(b, key...)
 	b = append(b, '=')
@@ -94,17 +115,52 @@ func appendTextValue(b []byte, value any) []byte {
 	switch v := value.(type) {
 	case string:
 		return appendEscapedString(b, v)
-	case int, int32, int64, uint, uint32, uint64, float32, float64, bool:
-		return appendJSONValue(b, v)
+	case Field:
+		if v.kind() == fieldString {
+			return appendEscapedString(b, v.str())
+		}
+		return v.appendPlain(b)
 	case []any:
 		return appendTextArray(b, v)
 	default:
-		// Fallback to fmt.Sprintf for complex types
-		return append(b, fmt.Sprintf("%v", v)...)
+		return appendPlainValue(b, v)
 	}
 }
 
-func appendEscapedString(b []byte, s string) []byte {
+// appendPlainValue appends the unquoted text representation of value to b.
+// Common types are rendered without reflection, other types fallback to
+// fmt.Append.
+func appendPlainValue(b []byte, value any) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(b, "<nil>"...)
+	case string:
+		return append(b, v...)
+	case Field:
+		return v.appendPlain(b)
+	case int, int32, int64, uint, uint32, uint64, float32, float64, bool:
+		return appendJSONValue(b, v)
+	case time.Duration:
+		return append(b, v.String()...)
+	case time.Time:
+		return v.AppendFormat(b, time.RFC3339Nano)
+	case codes.Code:
+		return append(b, v.String()...)
+	case []string:
+		b = append(b, '[')
+		for i, s := range v {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = append(b, s...)
+		}
+		return append(b, ']')
+	default:
+		return fmt.Append(b, v)
+	}
+}
+
+func appendEscapedString[S ~string | ~[]byte](b []byte, s S) []byte {
 	if needsQuoting(s) {
 		b = append(b, '"')
 		for i := 0; i < len(s); i++ {
@@ -120,7 +176,7 @@ func appendEscapedString(b []byte, s string) []byte {
 	return b
 }
 
-func needsQuoting(s string) bool {
+func needsQuoting[S ~string | ~[]byte](s S) bool {
 	if len(s) == 0 {
 		return true
 	}
@@ -144,16 +200,21 @@ func appendTextArray(b []byte, arr []any) []byte {
 }
 
 func appendJSONKeyValue(b []byte, key string, value any) []byte {
+	return appendJSONValue(appendJSONKey(b, key), value)
+}
+
+func appendJSONKey(b []byte, key string) []byte {
 	b = append(b, '"')
 	b = append(b, key...)
-	b = append(b, `":`...)
-	return appendJSONValue(b, value)
+	return append(b, `":`...)
 }
 
 func appendJSONValue(b []byte, value any) []byte {
 	switch v := value.(type) {
 	case string:
 		return appendJSONString(b, v)
+	case Field:
+		return v.appendJSON(b)
 	case int:
 		return strconv.AppendInt(b, int64(v), 10)
 	case int32:
@@ -174,8 +235,14 @@ func appendJSONValue(b []byte, value any) []byte {
 		return strconv.AppendBool(b, v)
 	case []any:
 		return appendJSONArray(b, v)
+	case []KV:
+		return appendJSONObject(b, v)
 	case time.Duration:
 		return appendJSONString(b, v.String())
+	case time.Time:
+		b = append(b, '"')
+		b = v.AppendFormat(b, time.RFC3339Nano)
+		return append(b, '"')
 	case codes.Code:
 		return appendJSONString(b, v.String())
 	default:
@@ -184,7 +251,7 @@ func appendJSONValue(b []byte, value any) []byte {
 	}
 }
 
-func appendJSONString(b []byte, s string) []byte {
+func appendJSONString[S ~string | ~[]byte](b []byte, s S) []byte {
 	b = append(b, '"')
 	for i := 0; i < len(s); i++ {
 		if s[i] < utf8.RuneSelf {
@@ -211,6 +278,17 @@ func appendJSONString(b []byte, s string) []byte {
 	return append(b, '"')
 }
 
+func appendJSONObject(b []byte, kvs []KV) []byte {
+	b = append(b, '{')
+	for i, kv := range kvs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONKeyValue(b, kv.K, kv.V)
+	}
+	return append(b, '}')
+}
+
 func appendJSONArray(b []byte, arr []any) []byte {
 	b = append(b, '[')
 	for i, v := range arr {
@@ -236,19 +314,44 @@ func FormatTerminal(e *Entry) []byte {
 	b = append(b, e.Severity.Color()...)
 	b = append(b, e.Severity.Code()...)
 	b = append(b, reset...)
-	b = fmt.Appendf(b, "[%04d]", int(e.Time.Sub(epoch)/time.Second))
-	if len(e.KeyVals) > 0 {
-		b = append(b, ' ')
-		for i, kv := range e.KeyVals {
-			b = append(b, e.Severity.Color()...)
-			b = append(b, kv.K...)
-			b = append(b, reset...)
-			b = fmt.Appendf(b, "=%v", kv.V)
-			if i < len(e.KeyVals)-1 {
-				b = append(b, ' ')
-			}
+	b = appendElapsed(b, int64(e.Time.Sub(epoch)/time.Second))
+	b = appendTerminalKeyVals(b, e.Severity.Color(), "", e.KeyVals)
+	b = append(b, '\n')
+	return b
+}
+
+// appendTerminalKeyVals appends the given key/value pairs each preceded with a
+// space and with the key colored with col. Groups are flattened and their keys
+// prefixed with the group name and a dot.
+func appendTerminalKeyVals(b []byte, col, prefix string, kvs []KV) []byte {
+	for _, kv := range kvs {
+		if g, ok := kv.V.([]KV); ok {
+			b = appendTerminalKeyVals(b, col, prefix+kv.K+".", g)
+			continue
 		}
+		b = append(b, ' ')
+		b = append(b, col...)
+		b = append(b, prefix...)
+		b = append(b, kv.K...)
+		b = append(b, reset...)
+		b = append(b, '=')
+		b = appendPlainValue(b, kv.V)
 	}
-	b = append(b, '\n')
 	return b
 }
+
+// appendElapsed appends the number of seconds formatted as "[%04d]" to b.
+func appendElapsed(b []byte, secs int64) []byte {
+	b = append(b, '[')
+	width := int64(1000)
+	if secs < 0 {
+		b = append(b, '-')
+		secs = -secs
+		width = 100
+	}
+	for p := width; p > 1 && secs < p; p /= 10 {
+		b = append(b, '0')
+	}
+	b = strconv.AppendInt(b, secs, 10)
+	return append(b, ']')
+}
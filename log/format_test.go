@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
 )
 
 func TestFormat(t *testing.T) {
@@ -290,3 +291,71 @@ func TestFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatTypedFields(t *testing.T) {
+	epoc := epoch
+	ts := time.Date(2022, time.January, 9, 20, 29, 45, 123, time.UTC)
+	epoch = ts.Add(-5 * time.Second)
+	defer func() { epoch = epoc }()
+	e := &Entry{
+		Time:     ts,
+		Severity: SeverityInfo,
+		KeyVals: kvList(nil).merge([]Fielder{
+			String("s", "a b"),
+			Int64("i", 1234),
+			Dur("d", 1500*time.Millisecond),
+			Time("t", ts),
+			Err(errors.New("boom")),
+			KV{"code", codes.NotFound},
+		}),
+	}
+
+	assert.Equal(t,
+		`time=2022-01-09T20:29:45Z level=info s="a b" i=1234 d=1.5s t=2022-01-09T20:29:45.000000123Z err=boom code=NotFound`+"\n",
+		string(FormatText(e)))
+	assert.Equal(t,
+		`{"time":"2022-01-09T20:29:45Z","level":"info","s":"a b","i":1234,"d":"1.5s","t":"2022-01-09T20:29:45.000000123Z","err":"boom","code":"NotFound"}`+"\n",
+		string(FormatJSON(e)))
+	col := SeverityInfo.Color()
+	assert.Equal(t,
+		col+"INFO\033[0m[0005] "+
+			col+"s\033[0m=a b "+
+			col+"i\033[0m=1234 "+
+			col+"d\033[0m=1.5s "+
+			col+"t\033[0m=2022-01-09T20:29:45.000000123Z "+
+			col+"err\033[0m=boom "+
+			col+"code\033[0m=NotFound\n",
+		string(FormatTerminal(e)))
+}
+
+func BenchmarkFormat(b *testing.B) {
+	ts := time.Date(2022, time.January, 9, 20, 29, 45, 123, time.UTC)
+	e := &Entry{
+		Time:     ts,
+		Severity: SeverityInfo,
+		KeyVals: kvList(nil).merge([]Fielder{
+			String(MessageKey, "request handled"),
+			String(HTTPMethodKey, "GET"),
+			Int64(HTTPStatusKey, 200),
+			Int64(HTTPBytesKey, 4096),
+			Dur("dur", 42*time.Millisecond),
+			Time("started", ts),
+		}),
+	}
+	formats := []struct {
+		name   string
+		format FormatFunc
+	}{
+		{"text", FormatText},
+		{"json", FormatJSON},
+		{"terminal", FormatTerminal},
+	}
+	for _, f := range formats {
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				f.format(e)
+			}
+		})
+	}
+}
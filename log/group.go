@@ -0,0 +1,120 @@
+package log
+
+import "context"
+
+// group is a named group opened with WithGroup and the key/value pairs added
+// to it with With.
+type group struct {
+	name    string
+	keyvals kvList
+}
+
+// Group returns a key/value pair that groups the key/value pairs given by
+// fielders under name. The value of the returned pair is a []KV. FormatJSON
+// renders groups as nested objects while FormatText and FormatTerminal render
+// the grouped keys prefixed with the group name and a dot:
+//
+//	log.Print(ctx, log.Group("db", log.KV{"table", "users"}, log.KV{"rows", 42}))
+//	// text: db.table=users db.rows=42
+//	// JSON: "db":{"table":"users","rows":42}
+//
+// Empty groups are rendered as empty objects by FormatJSON and omitted by the
+// other formats.
+func Group(name string, fielders ...Fielder) KV {
+	return KV{K: name, V: []KV(kvList(nil).merge(fielders))}
+}
+
+// WithGroup creates a copy of the given log context that groups all the
+// key/value pairs added later on with With or with the logging functions
+// under name. The message and error keys (MessageKey, ErrorMessageKey and the
+// error details keys) are never grouped. Calls to WithGroup can be nested.
+//
+// Usage:
+//
+//	ctx = log.WithGroup(ctx, "db")
+//	ctx = log.With(ctx, log.KV{"table", "users"})
+//	log.Print(ctx, log.KV{"msg", "query"}, log.KV{"rows", 42})
+//	// text: msg=query db.table=users db.rows=42
+func WithGroup(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return derive(ctx, func(l *logger) {
+		groups := make([]group, len(l.groups), len(l.groups)+1)
+		copy(groups, l.groups)
+		l.groups = append(groups, group{name: name})
+	})
+}
+
+// withGrouped returns a copy of groups where the given key/value pairs are
+// added to the last group.
+func withGrouped(groups []group, fielders []Fielder) []group {
+	res := make([]group, len(groups))
+	copy(res, groups)
+	last := &res[len(res)-1]
+	last.keyvals = last.keyvals.merge(fielders)
+	return res
+}
+
+// nestGroups returns the key/value pair that contains the key/value pairs of
+// the given groups nested according to the group hierarchy followed by kvs in
+// the innermost group. It returns false if all the groups are empty.
+func nestGroups(groups []group, kvs kvList) (KV, bool) {
+	content := kvs
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		c := make([]KV, 0, len(g.keyvals)+len(content))
+		c = append(c, g.keyvals...)
+		if i == len(groups)-1 {
+			c = append(c, content...)
+		} else if len(content) > 0 {
+			c = append(c, KV{K: groups[i+1].name, V: []KV(content)})
+		}
+		content = c
+	}
+	if len(content) == 0 {
+		return KV{}, false
+	}
+	return KV{K: groups[0].name, V: []KV(content)}, true
+}
+
+// ungroupedKey returns true if the given key is never grouped by WithGroup.
+func ungroupedKey(k string) bool {
+	switch k {
+	case MessageKey, ErrorMessageKey, ErrorChainKey, ErrorStackKey,
+		ErrorGRPCCodeKey, ErrorGRPCDetailsKey, GoaErrorNameKey, GoaErrorIDKey,
+		GoaErrorFaultKey, GoaErrorTemporaryKey, GoaErrorTimeoutKey:
+		return true
+	}
+	return false
+}
+
+// flattenGroups returns kvs where groups are replaced with their key/value
+// pairs prefixed with the group name and a dot. It returns kvs if it does not
+// contain any group.
+func flattenGroups(kvs []KV) []KV {
+	hasGroup := false
+	for _, kv := range kvs {
+		if _, ok := kv.V.([]KV); ok {
+			hasGroup = true
+			break
+		}
+	}
+	if !hasGroup {
+		return kvs
+	}
+	return appendFlattened(make([]KV, 0, len(kvs)), "", kvs)
+}
+
+// appendFlattened appends the flattened key/value pairs of kvs to dst
+// prefixing the keys with prefix.
+func appendFlattened(dst []KV, prefix string, kvs []KV) []KV {
+	for _, kv := range kvs {
+		if g, ok := kv.V.([]KV); ok {
+			dst = appendFlattened(dst, prefix+kv.K+".", g)
+			continue
+		}
+		dst = append(dst, KV{K: prefix + kv.K, V: kv.V})
+	}
+	return dst
+}
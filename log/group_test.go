@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	kvs := []Fielder{
+		KV{"msg", "query"},
+		Group("db", KV{"table", "users"}, KV{"rows", 42}, Group("conn", KV{"id", "c1"})),
+		Group("empty"),
+	}
+	cases := []struct {
+		name     string
+		format   FormatFunc
+		want     string
+		contains bool // terminal entries start with the time elapsed since the program started
+	}{
+		{"text", FormatText, `time=2022-02-22T17:00:00Z level=info msg=query db.table=users db.rows=42 db.conn.id=c1` + "\n", false},
+		{"json", FormatJSON, `{"time":"2022-02-22T17:00:00Z","level":"info","msg":"query","db":{"table":"users","rows":42,"conn":{"id":"c1"}},"empty":{}}` + "\n", false},
+		{"terminal", FormatTerminal, "msg\033[0m=query \033[34mdb.table\033[0m=users \033[34mdb.rows\033[0m=42 \033[34mdb.conn.id\033[0m=c1\n", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: c.format}))
+			FlushAndDisableBuffering(ctx)
+
+			Print(ctx, kvs...)
+
+			if c.contains {
+				assert.Contains(t, buf.String(), c.want)
+				return
+			}
+			assert.Equal(t, c.want, buf.String())
+		})
+	}
+}
+
+func TestWithGroup(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatJSON}))
+	FlushAndDisableBuffering(ctx)
+	ctx = With(ctx, KV{"svc", "api"})
+	dbCtx := WithGroup(ctx, "db")
+	dbCtx = With(dbCtx, KV{"table", "users"})
+	connCtx := WithGroup(dbCtx, "conn")
+
+	Print(dbCtx, KV{"msg", "query"}, KV{"rows", 42})
+	Print(connCtx, KV{"id", "c1"})
+	Errorf(connCtx, errors.New("boom"), "failed")
+	Print(WithGroup(WithGroup(ctx, "a"), "b"), KV{"msg", "empty groups are omitted"})
+	Print(ctx, KV{"msg", "parent is not grouped"})
+	Print(WithGroup(ctx, ""), KV{"msg", "empty name is ignored"})
+
+	want := `{"time":"2022-02-22T17:00:00Z","level":"info","svc":"api","msg":"query","db":{"table":"users","rows":42}}
+{"time":"2022-02-22T17:00:00Z","level":"info","svc":"api","db":{"table":"users","conn":{"id":"c1"}}}
+{"time":"2022-02-22T17:00:00Z","level":"error","svc":"api","err":"boom","msg":"failed","db":{"table":"users"}}
+{"time":"2022-02-22T17:00:00Z","level":"info","svc":"api","msg":"empty groups are omitted"}
+{"time":"2022-02-22T17:00:00Z","level":"info","svc":"api","msg":"parent is not grouped"}
+{"time":"2022-02-22T17:00:00Z","level":"info","svc":"api","msg":"empty name is ignored"}
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWithGroupBuffering(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatText}))
+	ctx = WithGroup(ctx, "g")
+
+	Info(ctx, KV{"k", buffered})
+	assert.Empty(t, buf.String())
+	FlushAndDisableBuffering(ctx)
+
+	assert.Equal(t, "time=2022-02-22T17:00:00Z level=info g.k=buffered\n", buf.String())
+}
+
+func TestGroupRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithRedactor(
+			RedactKeys("db.password", "secret"),
+			RedactValues(regexp.MustCompile(`\d{16}`))))
+	FlushAndDisableBuffering(ctx)
+	group := Group("db", KV{"user", "joe"}, KV{"password", "pwd"}, KV{"card", "4111111111111111"})
+	ctx = With(ctx, group)
+
+	Print(ctx, Group("secret", KV{"k", "v"}), Group("other", KV{"password", "visible"}))
+
+	assert.Equal(t, "time=2022-02-22T17:00:00Z level=info db.user=joe db.password=[REDACTED] db.card=[REDACTED] secret=[REDACTED] other.password=visible\n", buf.String())
+	assert.Equal(t, "pwd", group.V.([]KV)[1].V, "redaction must not modify the log context")
+}
+
+func TestGroupTruncation(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithMaxSize(4))
+	FlushAndDisableBuffering(ctx)
+	group := Group("g", KV{"k", "123456"})
+	ctx = With(ctx, group)
+
+	Print(ctx)
+
+	assert.Equal(t, "time=2022-02-22T17:00:00Z level=info g.k=\"1234 ... <clue/log.truncated>\" log.truncated=[g.k]\n", buf.String())
+	assert.Equal(t, "123456", group.V.([]KV)[0].V, "truncation must not modify the log context")
+}
+
+func TestFlattenGroups(t *testing.T) {
+	kvs := []KV{{"a", 1}}
+	assert.Equal(t, kvs, flattenGroups(kvs))
+
+	kvs = []KV{{"a", 1}, Group("g", KV{"b", 2}, Group("h", KV{"c", 3})), {"d", 4}}
+	assert.Equal(t, []KV{{"a", 1}, {"g.b", 2}, {"g.h.c", 3}, {"d", 4}}, flattenGroups(kvs))
+}
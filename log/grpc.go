@@ -4,8 +4,11 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"io"
 	"path"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
@@ -28,6 +31,11 @@ type (
 		disableCallLogging bool
 		disableCallID      bool
 		logFunc            func(ctx context.Context, keyvals ...Fielder)
+		flushIf            func(code codes.Code, duration time.Duration) bool
+		recovery           bool
+		panicHook          PanicHook
+		callIDKey          string
+		callIDFunc         func() string
 	}
 )
 
@@ -35,8 +43,11 @@ type (
 var shortID = randShortID
 
 // UnaryServerInterceptor returns a unary interceptor that performs two tasks:
-// 1. Enriches the request context with the logger specified in logCtx.
+// 1. Enriches the request context with the logger specified in logCtx. The
+// request ID is read from the x-request-id metadata if present and valid,
+// otherwise a new ID is generated.
 // 2. Logs details of the unary call, unless the WithDisableCallLogging option is provided.
+// Panics in the handler are recovered if WithCallRecovery is set.
 // UnaryServerInterceptor panics if logCtx was not created with Context.
 func UnaryServerInterceptor(logCtx context.Context, opts ...GRPCLogOption) grpc.UnaryServerInterceptor {
 	MustContainLogger(logCtx)
@@ -56,22 +67,28 @@ func UnaryServerInterceptor(logCtx context.Context, opts ...GRPCLogOption) grpc.
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (any, error) {
-		ctx = WithContext(ctx, logCtx)
+		ctx = fork(ctx, logCtx)
+		defer discard(ctx)
 		if !o.disableCallID {
-			ctx = With(ctx, KV{RequestIDKey, shortID()})
+			ctx = With(ctx, KV{RequestIDKey, o.callID(ctx)})
 		}
 		if o.disableCallLogging {
-			return handler(ctx, req)
+			then := time.Now()
+			res, err := o.unaryHandler(ctx, req, handler)
+			o.flushOrDiscard(ctx, err, timeSince(then))
+			return res, err
 		}
 		then := time.Now()
 		svcKV := KV{K: GRPCServiceKey, V: path.Dir(info.FullMethod)[1:]}
 		methKV := KV{K: GRPCMethodKey, V: path.Base(info.FullMethod)}
 		logFunc(ctx, KV{MessageKey, "start"}, svcKV, methKV)
 
-		res, err := handler(ctx, req)
+		res, err := o.unaryHandler(ctx, req, handler)
 
 		stat, _ := status.FromError(err)
-		ms := timeSince(then).Milliseconds()
+		duration := timeSince(then)
+		o.flushOrDiscard(ctx, err, duration)
+		ms := duration.Milliseconds()
 		codeKV := KV{K: GRPCCodeKey, V: stat.Code()}
 		durKV := KV{K: GRPCDurationKey, V: ms}
 		if o.iserr(stat.Code()) {
@@ -85,8 +102,11 @@ func UnaryServerInterceptor(logCtx context.Context, opts ...GRPCLogOption) grpc.
 }
 
 // StreamServerInterceptor returns a stream interceptor that performs two tasks:
-// 1. Enriches the request context with the logger specified in logCtx.
+// 1. Enriches the request context with the logger specified in logCtx. The
+// request ID is read from the x-request-id metadata if present and valid,
+// otherwise a new ID is generated.
 // 2. Logs details of the stream call, unless the WithDisableCallLogging option is provided.
+// Panics in the handler are recovered if WithCallRecovery is set.
 // StreamServerInterceptor panics if logCtx was not created with Context.
 func StreamServerInterceptor(logCtx context.Context, opts ...GRPCLogOption) grpc.StreamServerInterceptor {
 	MustContainLogger(logCtx)
@@ -106,37 +126,47 @@ func StreamServerInterceptor(logCtx context.Context, opts ...GRPCLogOption) grpc
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		ctx := WithContext(stream.Context(), logCtx)
+		ctx := fork(stream.Context(), logCtx)
+		defer discard(ctx)
 		if !o.disableCallID {
-			ctx = With(ctx, KV{RequestIDKey, shortID()})
+			ctx = With(ctx, KV{RequestIDKey, o.callID(ctx)})
 		}
-		stream = &streamWithContext{stream, ctx}
+		counted := &streamWithContext{ServerStream: stream, ctx: ctx}
+		stream = counted
 		if o.disableCallLogging {
-			return handler(srv, stream)
+			then := time.Now()
+			err := o.streamHandler(srv, stream, handler)
+			o.flushOrDiscard(ctx, err, timeSince(then))
+			return err
 		}
 		then := time.Now()
 		svcKV := KV{K: GRPCServiceKey, V: path.Dir(info.FullMethod)[1:]}
 		methKV := KV{K: GRPCMethodKey, V: path.Base(info.FullMethod)}
 		logFunc(ctx, KV{MessageKey, "start"}, svcKV, methKV)
 
-		err := handler(srv, stream)
+		err := o.streamHandler(srv, stream, handler)
 
 		stat, _ := status.FromError(err)
-		ms := timeSince(then).Milliseconds()
+		duration := timeSince(then)
+		o.flushOrDiscard(ctx, err, duration)
+		ms := duration.Milliseconds()
 		codeKV := KV{K: GRPCCodeKey, V: stat.Code()}
 		durKV := KV{K: GRPCDurationKey, V: ms}
+		sentKV := KV{K: GRPCSentKey, V: counted.sent.Load()}
+		recvKV := KV{K: GRPCReceivedKey, V: counted.received.Load()}
 		if o.iserr(stat.Code()) {
 			statKV := KV{K: GRPCStatusKey, V: stat.Message()}
-			Error(ctx, err, svcKV, methKV, statKV, codeKV, durKV)
+			Error(ctx, err, svcKV, methKV, statKV, codeKV, durKV, sentKV, recvKV)
 			return err
 		}
-		logFunc(ctx, KV{MessageKey, "end"}, svcKV, methKV, codeKV, durKV)
+		logFunc(ctx, KV{MessageKey, "end"}, svcKV, methKV, codeKV, durKV, sentKV, recvKV)
 		return err
 	}
 }
 
 // UnaryClientInterceptor returns a unary interceptor that logs the request with
-// the logger contained in the request context if any.
+// the logger contained in the request context if any. The request ID stored in
+// the context, if any, is propagated via the x-request-id metadata.
 func UnaryClientInterceptor(opts ...GRPCLogOption) grpc.UnaryClientInterceptor {
 	o := defaultGRPCOptions()
 	for _, opt := range opts {
@@ -156,6 +186,7 @@ func UnaryClientInterceptor(opts ...GRPCLogOption) grpc.UnaryClientInterceptor {
 		invoker grpc.UnaryInvoker,
 		opts ...grpc.CallOption,
 	) error {
+		ctx = o.propagateCallID(ctx)
 		then := time.Now()
 		svcKV := KV{K: GRPCServiceKey, V: path.Dir(fullmethod)[1:]}
 		methKV := KV{K: GRPCMethodKey, V: path.Base(fullmethod)}
@@ -178,7 +209,16 @@ func UnaryClientInterceptor(opts ...GRPCLogOption) grpc.UnaryClientInterceptor {
 }
 
 // StreamClientInterceptor returns a stream interceptor that logs the request
-// with the logger contained in the request context if any.
+// with the logger contained in the request context if any. The "end" entry is
+// logged once the stream finishes, that is when RecvMsg returns io.EOF or an
+// error, when SendMsg returns an error other than io.EOF or when the stream
+// context is canceled or its deadline expires, and includes the number of
+// messages sent and received. As with any gRPC client stream, callers must
+// either drain the stream by calling RecvMsg until it returns an error or
+// cancel its context: the "end" entry is not logged otherwise. Streams
+// canceled by the caller are logged at the same level as successful streams
+// rather than as errors. The request ID stored in the context, if any, is
+// propagated via the x-request-id metadata.
 func StreamClientInterceptor(opts ...GRPCLogOption) grpc.StreamClientInterceptor {
 	o := defaultGRPCOptions()
 	for _, opt := range opts {
@@ -198,24 +238,52 @@ func StreamClientInterceptor(opts ...GRPCLogOption) grpc.StreamClientInterceptor
 		streamer grpc.Streamer,
 		opts ...grpc.CallOption,
 	) (grpc.ClientStream, error) {
+		ctx = o.propagateCallID(ctx)
 		then := time.Now()
 		svcKV := KV{K: GRPCServiceKey, V: path.Dir(fullmethod)[1:]}
 		methKV := KV{K: GRPCMethodKey, V: path.Base(fullmethod)}
 		logFunc(ctx, KV{K: MessageKey, V: "start"}, svcKV, methKV)
 
 		stream, err := streamer(ctx, desc, cc, fullmethod, opts...)
-
-		stat, _ := status.FromError(err)
-		ms := timeSince(then).Milliseconds()
-		codeKV := KV{K: GRPCCodeKey, V: stat.Code()}
-		durKV := KV{K: GRPCDurationKey, V: ms}
-		if o.iserr(stat.Code()) {
-			statKV := KV{K: GRPCStatusKey, V: stat.Message()}
-			Error(ctx, err, svcKV, methKV, statKV, codeKV, durKV)
+		if err != nil {
+			stat, _ := status.FromError(err)
+			ms := timeSince(then).Milliseconds()
+			codeKV := KV{K: GRPCCodeKey, V: stat.Code()}
+			durKV := KV{K: GRPCDurationKey, V: ms}
+			if o.iserr(stat.Code()) {
+				statKV := KV{K: GRPCStatusKey, V: stat.Message()}
+				Error(ctx, err, svcKV, methKV, statKV, codeKV, durKV)
+				return stream, err
+			}
+			logFunc(ctx, KV{K: MessageKey, V: "end"}, svcKV, methKV, codeKV, durKV)
 			return stream, err
 		}
-		logFunc(ctx, KV{K: MessageKey, V: "end"}, svcKV, methKV, codeKV, durKV)
-		return stream, err
+
+		s := &loggedClientStream{
+			ClientStream:  stream,
+			serverStreams: desc.ServerStreams,
+			finish: func(err error, sent, received int64) {
+				stat, _ := status.FromError(err)
+				ms := timeSince(then).Milliseconds()
+				codeKV := KV{K: GRPCCodeKey, V: stat.Code()}
+				durKV := KV{K: GRPCDurationKey, V: ms}
+				sentKV := KV{K: GRPCSentKey, V: sent}
+				recvKV := KV{K: GRPCReceivedKey, V: received}
+				// Do not log streams canceled by the caller as errors:
+				// that would also flush and disable the buffer.
+				canceled := stat.Code() == codes.Canceled && errors.Is(ctx.Err(), context.Canceled)
+				if o.iserr(stat.Code()) && !canceled {
+					statKV := KV{K: GRPCStatusKey, V: stat.Message()}
+					Error(ctx, err, svcKV, methKV, statKV, codeKV, durKV, sentKV, recvKV)
+					return
+				}
+				logFunc(ctx, KV{K: MessageKey, V: "end"}, svcKV, methKV, codeKV, durKV, sentKV, recvKV)
+			},
+		}
+		s.stop = context.AfterFunc(ctx, func() {
+			s.done(status.FromContextError(ctx.Err()).Err())
+		})
+		return s, nil
 	}
 }
 
@@ -227,6 +295,19 @@ func WithErrorFunc(iserr func(codes.Code) bool) GRPCLogOption {
 	}
 }
 
+// WithCallFlushIf returns a GRPC logger option that flushes the log entries
+// buffered while handling a call if fn returns true once the call has been
+// handled. The buffered log entries are discarded otherwise. fn is called with
+// the status code returned by the handler and the time it took to handle the
+// call. The log entries still buffered once the call has been handled are
+// discarded whether or not this option is set. This option only applies to
+// server interceptors.
+func WithCallFlushIf(fn func(code codes.Code, duration time.Duration) bool) GRPCLogOption {
+	return func(o *grpcOptions) {
+		o.flushIf = fn
+	}
+}
+
 // WithDisableCallLogging returns a GRPC logger option that disables call
 // logging.
 func WithDisableCallLogging() GRPCLogOption {
@@ -236,7 +317,8 @@ func WithDisableCallLogging() GRPCLogOption {
 }
 
 // WithDisableCallID returns a GRPC logger option that disables the
-// generation of request IDs.
+// generation of request IDs in server interceptors and their propagation in
+// client interceptors.
 func WithDisableCallID() GRPCLogOption {
 	return func(o *grpcOptions) {
 		o.disableCallID = true
@@ -256,18 +338,111 @@ func defaultGRPCOptions() *grpcOptions {
 		iserr: func(c codes.Code) bool {
 			return c != codes.OK
 		},
+		callIDKey: DefaultRequestIDMetadataKey,
 	}
 }
 
-type streamWithContext struct {
-	grpc.ServerStream
-	ctx context.Context
+// flushOrDiscard flushes or discards the log entries buffered in ctx if
+// WithCallFlushIf is set.
+func (o *grpcOptions) flushOrDiscard(ctx context.Context, err error, duration time.Duration) {
+	if o.flushIf == nil {
+		return
+	}
+	flushOrDiscard(ctx, o.flushIf(status.Code(err), duration))
 }
 
+type (
+	// streamWithContext is a server stream that overrides the stream context
+	// and counts the messages sent and received.
+	streamWithContext struct {
+		grpc.ServerStream
+		ctx      context.Context
+		sent     atomic.Int64
+		received atomic.Int64
+	}
+
+	// loggedClientStream is a client stream that calls finish once the
+	// stream is done.
+	loggedClientStream struct {
+		grpc.ClientStream
+		serverStreams bool
+		finish        func(err error, sent, received int64)
+		// stop stops calling finish when the stream context is done.
+		stop     func() bool
+		once     sync.Once
+		sent     atomic.Int64
+		received atomic.Int64
+	}
+)
+
 func (s *streamWithContext) Context() context.Context {
 	return s.ctx
 }
 
+// SendMsg sends m and counts it if successful.
+func (s *streamWithContext) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+	}
+	return err
+}
+
+// RecvMsg receives m and counts it if successful.
+func (s *streamWithContext) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received.Add(1)
+	}
+	return err
+}
+
+// SendMsg sends m and counts it if successful. It logs the end of the stream
+// if SendMsg returned an error other than io.EOF: gRPC aborts the stream in
+// this case while io.EOF means that the status must be retrieved with
+// RecvMsg.
+func (s *loggedClientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	switch {
+	case err == nil:
+		s.sent.Add(1)
+	case err != io.EOF:
+		s.end(err)
+	}
+	return err
+}
+
+// RecvMsg receives m, counts it if successful and logs the end of the stream
+// if it is done: RecvMsg returned io.EOF or an error, or the server does not
+// stream and the response was received.
+func (s *loggedClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.received.Add(1)
+		if !s.serverStreams {
+			s.end(nil)
+		}
+	case err == io.EOF:
+		s.end(nil)
+	default:
+		s.end(err)
+	}
+	return err
+}
+
+// end releases the context callback and calls finish once. It must not be
+// called from the context callback.
+func (s *loggedClientStream) end(err error) {
+	s.stop()
+	s.done(err)
+}
+
+// done calls finish once.
+func (s *loggedClientStream) done(err error) {
+	s.once.Do(func() { s.finish(err, s.sent.Load(), s.received.Load()) })
+}
+
 // randShortID produces a "unique" 6 bytes long string.
 // This algorithm favors simplicity and efficiency over true uniqueness.
 func randShortID() string {
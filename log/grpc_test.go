@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -90,8 +92,8 @@ func TestStreamServerTrace(t *testing.T) {
 
 	prefix := `{"time":"2022-01-09T20:29:45Z","level":"info","request_id":"test-request-id","msg":"start","grpc.service":"test.Test","grpc.method":"GrpcStream"}`
 	logged := `{"time":"2022-01-09T20:29:45Z","level":"info","request_id":"test-request-id","key1":"value1","key2":"value2"}`
-	suffix := `{"time":"2022-01-09T20:29:45Z","level":"info","request_id":"test-request-id","msg":"end","grpc.service":"test.Test","grpc.method":"GrpcStream","grpc.code":"OK","grpc.time_ms":XXX}`
-	errors := `{"time":"2022-01-09T20:29:45Z","level":"error","request_id":"test-request-id","err":"rpc error: code = Unknown desc = test-error","grpc.service":"test.Test","grpc.method":"GrpcStream","grpc.status":"test-error","grpc.code":"Unknown","grpc.time_ms":XXX}`
+	suffix := `{"time":"2022-01-09T20:29:45Z","level":"info","request_id":"test-request-id","msg":"end","grpc.service":"test.Test","grpc.method":"GrpcStream","grpc.code":"OK","grpc.time_ms":XXX,"grpc.sent":1,"grpc.received":1}`
+	errors := `{"time":"2022-01-09T20:29:45Z","level":"error","request_id":"test-request-id","err":"rpc error: code = Unknown desc = test-error","grpc.service":"test.Test","grpc.method":"GrpcStream","grpc.status":"test-error","grpc.code":"Unknown","grpc.time_ms":XXX,"grpc.sent":0,"grpc.received":1}`
 
 	cases := []struct {
 		name        string
@@ -218,14 +220,19 @@ func TestUnaryClientInterceptor(t *testing.T) {
 func TestStreamClientInterceptor(t *testing.T) {
 	startLog := `time=2022-01-09T20:29:45Z level=info msg=start grpc.service=test.Test grpc.method=GrpcStream`
 	endLog := `time=2022-01-09T20:29:45Z level=info msg=end grpc.service=test.Test grpc.method=GrpcStream grpc.code=OK grpc.time_ms=42`
+	errorLog := `time=2022-01-09T20:29:45Z level=error err="rpc error: code = Unknown desc = test-error" grpc.service=test.Test grpc.method=GrpcStream grpc.status=test-error grpc.code=Unknown grpc.time_ms=42 grpc.sent=1 grpc.received=0`
 
 	cases := []struct {
 		name     string
 		noLog    bool
+		method   func(context.Context, testsvc.Stream) error
+		send     bool
 		expected string
 	}{
-		{"no logger", true, ""},
-		{"success", false, startLog + "\n" + endLog},
+		{"no logger", true, dummyStreamMethod(), false, ""},
+		{"success", false, dummyStreamMethod(), false, startLog + "\n" + endLog + " grpc.sent=0 grpc.received=0"},
+		{"echo", false, echoMethod, true, startLog + "\n" + endLog + " grpc.sent=1 grpc.received=1"},
+		{"error", false, echoErrorMethod, true, startLog + "\n" + errorLog},
 	}
 	now := timeNow
 	timeNow = func() time.Time { return time.Date(2022, time.January, 9, 20, 29, 45, 0, time.UTC) }
@@ -244,19 +251,142 @@ func TestStreamClientInterceptor(t *testing.T) {
 			}
 			cli, stop := testsvc.SetupGRPC(t,
 				testsvc.WithDialOptions(grpc.WithStreamInterceptor(StreamClientInterceptor())),
-				testsvc.WithStreamFunc(dummyStreamMethod()))
+				testsvc.WithStreamFunc(c.method))
 
-			_, err := cli.GRPCStream(ctx)
-			if err != nil {
-				t.Errorf("unexpected stream error: %v", err)
+			stream, err := cli.GRPCStream(ctx)
+			require.NoError(t, err)
+			if !c.noLog {
+				assert.Equal(t, startLog, strings.TrimSpace(buf.String()), "end must not be logged before the stream is done")
+			}
+			if c.send {
+				require.NoError(t, stream.Send(&testsvc.Fields{}))
+			}
+			for err == nil {
+				_, err = stream.Recv()
 			}
 			stop()
 
-			assert.Equal(t, strings.TrimSpace(buf.String()), c.expected)
+			assert.Equal(t, c.expected, strings.TrimSpace(buf.String()))
+		})
+	}
+}
+
+func TestStreamClientInterceptorClientStreaming(t *testing.T) {
+	var logged []*Entry
+	ctx := Context(context.Background(), WithOutputs(Output{Handle: func(e *Entry) error {
+		logged = append(logged, e)
+		return nil
+	}}))
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &testClientStream{}, nil
+	}
+
+	stream, err := StreamClientInterceptor()(ctx, &grpc.StreamDesc{ClientStreams: true}, nil, "/test.Test/Upload", streamer)
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(nil))
+	require.NoError(t, stream.SendMsg(nil))
+	require.NoError(t, stream.CloseSend())
+	require.Len(t, logged, 1)
+	require.NoError(t, stream.RecvMsg(nil))
+	require.NoError(t, stream.RecvMsg(nil)) // must not log twice
+
+	require.Len(t, logged, 2)
+	assert.Equal(t, KV{MessageKey, "end"}, logged[1].KeyVals[0])
+	assert.Contains(t, logged[1].KeyVals, KV{GRPCSentKey, int64(2)})
+	assert.Contains(t, logged[1].KeyVals, KV{GRPCReceivedKey, int64(1)})
+}
+
+func TestStreamClientInterceptorCanceled(t *testing.T) {
+	var (
+		lock   sync.Mutex
+		logged []*Entry
+	)
+	ctx := Context(context.Background(), WithOutputs(Output{Handle: func(e *Entry) error {
+		lock.Lock()
+		defer lock.Unlock()
+		logged = append(logged, e)
+		return nil
+	}}))
+	ctx, cancel := context.WithCancel(ctx)
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &testClientStream{}, nil
+	}
+
+	stream, err := StreamClientInterceptor()(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/test.Test/Watch", streamer)
+	require.NoError(t, err)
+	require.NoError(t, stream.RecvMsg(nil))
+	cancel()
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(logged) == 2
+	}, time.Second, time.Millisecond)
+	require.NoError(t, stream.RecvMsg(nil)) // must not log twice
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, logged, 2)
+	assert.Equal(t, SeverityInfo, logged[1].Severity, "caller cancellation must not be logged as an error")
+	assert.Equal(t, KV{MessageKey, "end"}, logged[1].KeyVals[0])
+	assert.Contains(t, logged[1].KeyVals, KV{GRPCCodeKey, codes.Canceled})
+	assert.Contains(t, logged[1].KeyVals, KV{GRPCReceivedKey, int64(1)})
+}
+
+func TestStreamClientInterceptorErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		cancel   bool
+		sendErr  error
+		recvErr  error
+		wantSev  Severity
+		wantCode codes.Code
+	}{
+		{"recv canceled by caller", true, nil, status.Error(codes.Canceled, "canceled"), SeverityInfo, codes.Canceled},
+		{"recv canceled by server", false, nil, status.Error(codes.Canceled, "canceled"), SeverityError, codes.Canceled},
+		{"send error", false, status.Error(codes.Internal, "boom"), nil, SeverityError, codes.Internal},
+		{"send EOF", false, io.EOF, status.Error(codes.Unavailable, "gone"), SeverityError, codes.Unavailable},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var logged []*Entry
+			ctx := Context(context.Background(), WithOutputs(Output{Handle: func(e *Entry) error {
+				logged = append(logged, e)
+				return nil
+			}}))
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+				return &testClientStream{sendErr: c.sendErr, recvErr: c.recvErr}, nil
+			}
+
+			stream, err := StreamClientInterceptor()(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, nil, "/test.Test/Chat", streamer)
+			require.NoError(t, err)
+			if c.cancel {
+				cancel()
+			}
+			if err := stream.SendMsg(nil); err == nil || err == io.EOF {
+				assert.Error(t, stream.RecvMsg(nil))
+			}
+			cancel()
+			require.Len(t, logged, 2, "end must be logged once")
+			assert.Equal(t, c.wantSev, logged[1].Severity)
+			assert.Contains(t, logged[1].KeyVals, KV{GRPCCodeKey, c.wantCode})
 		})
 	}
 }
 
+// testClientStream is a grpc.ClientStream whose methods return the
+// configured errors.
+type testClientStream struct {
+	grpc.ClientStream
+	sendErr error
+	recvErr error
+}
+
+func (s *testClientStream) SendMsg(any) error { return s.sendErr }
+func (s *testClientStream) RecvMsg(any) error { return s.recvErr }
+func (*testClientStream) CloseSend() error    { return nil }
+
 func TestWithCallLogFunc(t *testing.T) {
 	var loggedKeyvals []Fielder
 	customLogFunc := func(ctx context.Context, keyvals ...Fielder) {
@@ -339,6 +469,56 @@ func TestWithCallLogFuncClient(t *testing.T) {
 	stop()
 }
 
+func TestWithCallFlushIf(t *testing.T) {
+	cases := []struct {
+		name    string
+		code    codes.Code
+		flushed bool
+	}{
+		{"ok", codes.OK, false},
+		{"internal", codes.Internal, true},
+		{"not found", codes.NotFound, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var gotCode codes.Code
+			flushIf := func(code codes.Code, _ time.Duration) bool {
+				gotCode = code
+				return code == codes.Internal
+			}
+			var buf Buffer
+			ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: testFormat}))
+			interceptor := UnaryServerInterceptor(ctx,
+				WithCallFlushIf(flushIf),
+				WithDisableCallLogging(),
+				WithDisableCallID())
+			cli, stop := testsvc.SetupGRPC(t,
+				testsvc.WithServerOptions(grpc.UnaryInterceptor(interceptor)),
+				testsvc.WithUnaryFunc(bufferUnaryMethod(c.code)))
+			cli.GRPCMethod(context.Background(), &testsvc.Fields{}) // nolint:errcheck
+			stop()
+
+			assert.Equal(t, c.code, gotCode)
+			assert.Empty(t, entries(ctx))
+			if c.flushed {
+				assert.Equal(t, buffered, buf.String())
+			} else {
+				assert.Empty(t, buf.String())
+			}
+		})
+	}
+}
+
+func bufferUnaryMethod(code codes.Code) func(context.Context, *testsvc.Fields) (*testsvc.Fields, error) {
+	return func(ctx context.Context, _ *testsvc.Fields) (*testsvc.Fields, error) {
+		Infof(ctx, buffered)
+		if code != codes.OK {
+			return nil, status.Error(code, code.String())
+		}
+		return &testsvc.Fields{}, nil
+	}
+}
+
 func logUnaryMethod(ctx context.Context, fields *testsvc.Fields) (*testsvc.Fields, error) {
 	Print(ctx, KV{"key1", "value1"}, KV{"key2", "value2"})
 	return silentUnaryMethod(ctx, fields)
@@ -382,3 +562,57 @@ func dummyStreamMethod() func(context.Context, testsvc.Stream) error {
 		return stream.Close()
 	}
 }
+
+func TestWithCallRecovery(t *testing.T) {
+	var hookValue any
+	hook := func(_ context.Context, p any, _ []byte) { hookValue = p }
+	newCtx := func(logged *[]*Entry) context.Context {
+		return Context(context.Background(), WithOutputs(Output{Handle: func(e *Entry) error {
+			*logged = append(*logged, e)
+			return nil
+		}}))
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		hookValue = nil
+		var logged []*Entry
+		ctx := newCtx(&logged)
+		interceptor := UnaryServerInterceptor(ctx, WithCallRecovery(hook), WithDisableCallLogging(), WithDisableCallID())
+		cli, stop := testsvc.SetupGRPC(t,
+			testsvc.WithServerOptions(grpc.UnaryInterceptor(interceptor)),
+			testsvc.WithUnaryFunc(func(ctx context.Context, _ *testsvc.Fields) (*testsvc.Fields, error) {
+				Infof(ctx, buffered)
+				panic("boom")
+			}))
+		_, err := cli.GRPCMethod(context.Background(), &testsvc.Fields{})
+		stop()
+
+		assert.ErrorContains(t, err, "internal error")
+		assert.Equal(t, "boom", hookValue)
+		require.Len(t, logged, 2)
+		assert.Equal(t, kvList{{MessageKey, buffered}}, logged[0].KeyVals)
+		assert.Equal(t, SeverityError, logged[1].Severity)
+		assert.Equal(t, KV{PanicKey, "boom"}, logged[1].KeyVals[2])
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		hookValue = nil
+		var logged []*Entry
+		ctx := newCtx(&logged)
+		interceptor := StreamServerInterceptor(ctx, WithCallRecovery(hook), WithDisableCallLogging(), WithDisableCallID())
+		cli, stop := testsvc.SetupGRPC(t,
+			testsvc.WithServerOptions(grpc.StreamInterceptor(interceptor)),
+			testsvc.WithStreamFunc(func(context.Context, testsvc.Stream) error {
+				panic("boom")
+			}))
+		stream, err := cli.GRPCStream(context.Background())
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		stop()
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "boom", hookValue)
+		require.Len(t, logged, 1)
+		assert.Equal(t, KV{PanicKey, "boom"}, logged[0].KeyVals[2])
+	})
+}
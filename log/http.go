@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"regexp"
+	"time"
 
 	goa "goa.design/goa/v3/pkg"
 )
@@ -28,11 +29,18 @@ type (
 		disableRequestLogging bool
 		disableRequestID      bool
 		logFunc               func(ctx context.Context, keyvals ...Fielder)
+		flushIf               func(status int, duration time.Duration) bool
+		recovery              bool
+		panicHook             PanicHook
+		capture               captureOptions
+		requestIDHeader       string
+		requestIDFunc         func() string
 	}
 
 	httpClientOptions struct {
-		iserr      func(int) bool
-		logErrBody bool
+		iserr           func(int) bool
+		logErrBody      bool
+		requestIDHeader string
 	}
 
 	// client wraps an HTTP roundtripper and logs requests and responses.
@@ -42,23 +50,35 @@ type (
 	}
 
 	// responseCapture is a http.ResponseWriter which captures the response status
-	// code and content length.
+	// code, content length and optionally the beginning of the body.
 	responseCapture struct {
 		http.ResponseWriter
 		StatusCode    int
 		ContentLength int
+		body          *limitedBuffer
 	}
 )
 
 // HTTP returns a HTTP middleware that performs two tasks:
 //  1. Enriches the request context with the logger specified in logCtx.
+//     The request ID is read from the X-Request-ID header if present and valid,
+//     otherwise a new ID is generated.
 //  2. Logs HTTP request details, except when WithDisableRequestLogging is set or
 //     URL path matches a WithPathFilter regex.
 //
+// If WithFlushIf is set the buffered log entries are flushed or discarded once
+// the request has been handled depending on the response status and duration.
+// The log entries still buffered once the request has been handled are
+// discarded.
+// If WithRecovery is set panics in the handler are recovered and logged.
+// WithRequestHeaders, WithResponseHeaders, WithRequestBody and
+// WithResponseBody add the corresponding request details to the "end" log
+// entry subject to WithCaptureMode.
+//
 // HTTP panics if logCtx was not created with Context.
 func HTTP(logCtx context.Context, opts ...HTTPLogOption) func(http.Handler) http.Handler {
 	MustContainLogger(logCtx)
-	var options httpLogOptions
+	options := httpLogOptions{requestIDHeader: DefaultRequestIDHeader}
 	for _, o := range opts {
 		if o != nil {
 			o(&options)
@@ -71,34 +91,66 @@ func HTTP(logCtx context.Context, opts ...HTTPLogOption) func(http.Handler) http
 	}
 
 	return func(h http.Handler) http.Handler {
+		serve := h.ServeHTTP
+		if options.recovery {
+			serve = recoverHTTP(h, logCtx, options.panicHook)
+		}
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			for _, opt := range options.pathFilters {
 				if opt.MatchString(req.URL.Path) {
-					h.ServeHTTP(w, req)
+					serve(w, req)
 					return
 				}
 			}
-			ctx := WithContext(req.Context(), logCtx)
+			ctx := fork(req.Context(), logCtx)
+			defer discard(ctx)
 			if !options.disableRequestID {
-				ctx = With(ctx, KV{RequestIDKey, shortID()})
+				ctx = With(ctx, KV{RequestIDKey, options.requestID(req)})
 			}
-			if options.disableRequestLogging {
-				h.ServeHTTP(w, req.WithContext(ctx))
+			if options.disableRequestLogging && options.flushIf == nil {
+				serve(w, req.WithContext(ctx))
 				return
 			}
 			methKV := KV{K: HTTPMethodKey, V: req.Method}
 			urlKV := KV{K: HTTPURLKey, V: req.URL.String()}
-			fromKV := KV{K: HTTPFromKey, V: from(req)}
-			logFunc(ctx, KV{K: MessageKey, V: "start"}, methKV, urlKV, fromKV)
+			if !options.disableRequestLogging {
+				fromKV := KV{K: HTTPFromKey, V: from(req)}
+				logFunc(ctx, KV{K: MessageKey, V: "start"}, methKV, urlKV, fromKV)
+			}
 
 			rw := &responseCapture{ResponseWriter: w}
+			req = req.WithContext(ctx)
+			var (
+				capture   = !options.disableRequestLogging && options.capture.enabled()
+				reqHeader http.Header
+				reqBody   *bodyCapture
+			)
+			if capture {
+				reqHeader = req.Header.Clone()
+				reqBody = options.capture.start(ctx, req, rw)
+			}
 			started := timeNow()
-			h.ServeHTTP(rw, req.WithContext(ctx))
+			serve(rw, req)
+			duration := timeSince(started)
 
+			if options.flushIf != nil {
+				status := rw.StatusCode
+				if status == 0 {
+					status = http.StatusOK
+				}
+				flushOrDiscard(ctx, options.flushIf(status, duration))
+			}
+			if options.disableRequestLogging {
+				return
+			}
 			statusKV := KV{K: HTTPStatusKey, V: rw.StatusCode}
-			durKV := KV{K: HTTPDurationKey, V: timeSince(started).Milliseconds()}
+			durKV := KV{K: HTTPDurationKey, V: duration.Milliseconds()}
 			bytesKV := KV{K: HTTPBytesKey, V: rw.ContentLength}
-			logFunc(ctx, KV{K: MessageKey, V: "end"}, methKV, urlKV, statusKV, durKV, bytesKV)
+			kvs := []Fielder{KV{K: MessageKey, V: "end"}, methKV, urlKV, statusKV, durKV, bytesKV}
+			if capture {
+				kvs = append(kvs, options.capture.keyvals(ctx, reqHeader, reqBody, rw)...)
+			}
+			logFunc(ctx, kvs...)
 		})
 	}
 }
@@ -118,10 +170,12 @@ func Endpoint(e goa.Endpoint) goa.Endpoint {
 }
 
 // Client wraps the given roundtripper and log requests and responses using the
-// clue logger stored in the request context.
+// clue logger stored in the request context. The request ID stored in the
+// context, if any, is propagated via the X-Request-ID header.
 func Client(t http.RoundTripper, opts ...HTTPClientLogOption) http.RoundTripper {
 	options := &httpClientOptions{
-		iserr: func(status int) bool { return status >= 400 },
+		iserr:           func(status int) bool { return status >= 400 },
+		requestIDHeader: DefaultRequestIDHeader,
 	}
 	for _, o := range opts {
 		o(options)
@@ -138,6 +192,23 @@ func WithPathFilter(filter *regexp.Regexp) HTTPLogOption {
 	}
 }
 
+// WithFlushIf returns a HTTP middleware option that flushes the log entries
+// buffered while handling a request if fn returns true once the request has
+// been handled. The buffered log entries are discarded otherwise. fn is called
+// with the response status code and the time it took to handle the request.
+//
+// Usage:
+//
+//	// Flush log entries of failed or slow requests.
+//	handler = log.HTTP(ctx, log.WithFlushIf(func(status int, d time.Duration) bool {
+//	    return status >= 500 || status == http.StatusConflict || d > time.Second
+//	}))(handler)
+func WithFlushIf(fn func(status int, duration time.Duration) bool) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.flushIf = fn
+	}
+}
+
 // WithErrorStatus returns a HTTP client logger option that configures the
 // logger to log errors for responses with the given status code.
 func WithErrorStatus(status int) HTTPClientLogOption {
@@ -185,7 +256,7 @@ func (c *client) RoundTrip(req *http.Request) (resp *http.Response, err error) {
 	methKV := KV{K: HTTPMethodKey, V: req.Method}
 	urlKV := KV{K: HTTPURLKey, V: req.URL.String()}
 	then := timeNow()
-	resp, err = c.RoundTripper.RoundTrip(req)
+	resp, err = c.RoundTripper.RoundTrip(c.options.propagateRequestID(req))
 	if err != nil {
 		Error(req.Context(), err, msgKV, methKV, urlKV)
 		return
@@ -217,10 +288,14 @@ func (w *responseCapture) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
-// Write computes the written len and stores it in ContentLength.
+// Write computes the written len and stores it in ContentLength. It also
+// records the written bytes if the response body is captured.
 func (w *responseCapture) Write(b []byte) (int, error) {
 	n, err := w.ResponseWriter.Write(b)
 	w.ContentLength += n
+	if w.body != nil {
+		w.body.write(b[:n])
+	}
 	return n, err
 }
 
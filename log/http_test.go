@@ -3,6 +3,7 @@ package log
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	goa "goa.design/goa/v3/pkg"
 )
 
@@ -205,6 +207,82 @@ func TestWithRequestLogFunc(t *testing.T) {
 	assert.Empty(t, buf.String())
 }
 
+func TestWithFlushIf(t *testing.T) {
+	since := timeSince
+	defer func() { timeSince = since }()
+	shortID = func() string { return "test-request-id" }
+	defer func() { shortID = randShortID }()
+
+	cases := []struct {
+		name     string
+		status   int
+		duration time.Duration
+		flushed  bool
+	}{
+		{"ok", http.StatusOK, time.Millisecond, false},
+		{"server error", http.StatusInternalServerError, time.Millisecond, true},
+		{"selected client error", http.StatusConflict, time.Millisecond, true},
+		{"other client error", http.StatusNotFound, time.Millisecond, false},
+		{"slow", http.StatusOK, 2 * time.Second, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			timeSince = func(time.Time) time.Duration { return c.duration }
+			var gotStatus int
+			var gotDuration time.Duration
+			flushIf := func(status int, d time.Duration) bool {
+				gotStatus, gotDuration = status, d
+				return status >= 500 || status == http.StatusConflict || d > time.Second
+			}
+			var reqCtx context.Context
+			var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				reqCtx = req.Context()
+				Infof(req.Context(), buffered)
+				if c.status != http.StatusOK {
+					w.WriteHeader(c.status)
+				}
+			})
+			var buf bytes.Buffer
+			ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: testFormat}))
+			handler = HTTP(ctx, WithFlushIf(flushIf), WithDisableRequestLogging())(handler)
+
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, c.status, gotStatus)
+			assert.Equal(t, c.duration, gotDuration)
+			assert.Empty(t, entries(reqCtx))
+			if c.flushed {
+				assert.Equal(t, "test-request-id"+buffered, buf.String())
+			} else {
+				assert.Empty(t, buf.String())
+			}
+		})
+	}
+}
+
+func TestHTTPRequestBuffers(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatText}))
+	Infof(ctx, "startup")
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		Infof(req.Context(), "%s", req.URL.Path)
+		if req.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	flushIf := func(status int, _ time.Duration) bool { return status >= 500 }
+	handler = HTTP(ctx, WithFlushIf(flushIf), WithDisableRequestLogging(), WithDisableRequestID())(handler)
+
+	for _, path := range []string{"/ok", "/fail"} {
+		req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, "time=2022-02-22T17:00:00Z level=info msg=/fail\n", buf.String())
+	assert.Len(t, entries(ctx), 1, "requests must not share the buffer of the server log context")
+}
+
 type errorClient struct {
 	err error
 }
@@ -212,3 +290,101 @@ type errorClient struct {
 func (c *errorClient) RoundTrip(req *http.Request) (*http.Response, error) {
 	return nil, c.err
 }
+
+func TestWithRecovery(t *testing.T) {
+	cases := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{"panic", func(w http.ResponseWriter, req *http.Request) {
+			Infof(req.Context(), buffered)
+			panic("boom")
+		}, http.StatusInternalServerError, ""},
+		{"panic after write", func(w http.ResponseWriter, req *http.Request) {
+			Infof(req.Context(), buffered)
+			w.Write([]byte("partial")) // nolint: errcheck
+			panic("boom")
+		}, http.StatusOK, "partial"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var logged []*Entry
+			ctx := Context(context.Background(), WithOutputs(Output{Handle: func(e *Entry) error {
+				logged = append(logged, e)
+				return nil
+			}}), WithTruncation(TruncationPolicy{})) // keep the whole stack
+			var hookValue any
+			var hookStack []byte
+			hook := func(_ context.Context, p any, stack []byte) { hookValue, hookStack = p, stack }
+			handler := HTTP(ctx, WithRecovery(hook), WithDisableRequestLogging(), WithDisableRequestID())(c.handler)
+
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.wantStatus, rec.Code)
+			assert.Equal(t, c.wantBody, rec.Body.String())
+			assert.Equal(t, "boom", hookValue)
+			assert.Contains(t, string(hookStack), "TestWithRecovery")
+			require.Len(t, logged, 2)
+			assert.Equal(t, kvList{{MessageKey, buffered}}, logged[0].KeyVals, "buffered entries must be flushed")
+			e := logged[1]
+			assert.Equal(t, SeverityError, e.Severity)
+			require.Len(t, e.KeyVals, 4)
+			assert.Equal(t, KV{ErrorMessageKey, "panic: boom"}, e.KeyVals[0])
+			assert.Equal(t, KV{MessageKey, "panic"}, e.KeyVals[1])
+			assert.Equal(t, KV{PanicKey, "boom"}, e.KeyVals[2])
+			assert.Equal(t, PanicStackKey, e.KeyVals[3].K)
+			assert.Contains(t, e.KeyVals[3].V, "TestWithRecovery")
+		})
+	}
+}
+
+func TestWithRecoveryRequestLogging(t *testing.T) {
+	shortID = func() string { return "test-request-id" }
+	defer func() { shortID = randShortID }()
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatText}))
+	handler := HTTP(ctx, WithRecovery(nil))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(errors.New("boom"))
+	}))
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, buf.String(), `level=error request_id=test-request-id err=boom msg=panic panic=boom`)
+	assert.Contains(t, buf.String(), `msg=end http.method=GET http.url=http://example.com http.status=500`)
+}
+
+func TestWithRecoveryFilteredPath(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatText}))
+	var hookCtx context.Context
+	hook := func(ctx context.Context, _ any, _ []byte) { hookCtx = ctx }
+	handler := HTTP(ctx, WithRecovery(hook), WithPathFilter(regexp.MustCompile("^/healthz$")))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://example.com/healthz", nil)
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, buf.String(), `level=error err="panic: boom" msg=panic panic=boom panic.stack=`)
+	assert.NotContains(t, buf.String(), "msg=start", "filtered requests must not be logged")
+	require.NotNil(t, hookCtx)
+	assert.NotNil(t, hookCtx.Value(ctxLogger), "the hook must receive a context with a logger")
+}
+
+func TestWithRecoveryAbortHandler(t *testing.T) {
+	ctx := Context(context.Background())
+	handler := HTTP(ctx, WithRecovery(nil), WithDisableRequestLogging())(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { handler.ServeHTTP(httptest.NewRecorder(), req) })
+}
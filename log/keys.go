@@ -20,6 +20,30 @@ var (
 	GRPCCodeKey     = "grpc.code"
 	GRPCStatusKey   = "grpc.status"
 	GRPCDurationKey = "grpc.time_ms"
+	GRPCSentKey     = "grpc.sent"
+	GRPCReceivedKey = "grpc.received"
 	GoaServiceKey   = "goa.service"
 	GoaMethodKey    = "goa.method"
+	PanicKey        = "panic"
+	PanicStackKey   = "panic.stack"
+
+	ErrorChainKey        = "err.chain"
+	ErrorStackKey        = "err.stack"
+	ErrorGRPCCodeKey     = "err.grpc.code"
+	ErrorGRPCDetailsKey  = "err.grpc.details"
+	GoaErrorNameKey      = "goa.error.name"
+	GoaErrorIDKey        = "goa.error.id"
+	GoaErrorFaultKey     = "goa.error.fault"
+	GoaErrorTemporaryKey = "goa.error.temporary"
+	GoaErrorTimeoutKey   = "goa.error.timeout"
+
+	HTTPRequestHeaderKeyPrefix  = "http.request.header."
+	HTTPResponseHeaderKeyPrefix = "http.response.header."
+	HTTPRequestBodyKey          = "http.request.body"
+	HTTPResponseBodyKey         = "http.response.body"
+
+	DroppedEntriesKey    = "log.dropped"
+	SuppressedEntriesKey = "log.suppressed"
+	SuppressedMessageKey = "log.suppressed_msg"
+	TruncatedKeysKey     = "log.truncated"
 )
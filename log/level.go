@@ -0,0 +1,99 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// LevelVar is a severity threshold that can be changed while the program
+// runs. Bind a LevelVar to a log context with WithLevelVar: changing its value
+// takes effect immediately for the context and all the contexts derived from
+// it, including the request contexts created by the HTTP middleware and gRPC
+// interceptors. It is safe for concurrent use. The zero value is
+// SeverityInfo.
+//
+// Usage:
+//
+//	var level log.LevelVar
+//	ctx := log.Context(context.Background(), log.WithLevelVar(&level))
+//	// ...
+//	level.Set(log.SeverityWarn) // only log warnings and errors from now on
+type LevelVar struct {
+	sev atomic.Int64
+}
+
+// WithLevelVar binds the log context to the given severity threshold: entries
+// with a lower severity are not logged. Setting the threshold to SeverityDebug
+// enables debug logging and disables buffering as WithDebug does until the
+// threshold is raised again. WithDebug enables debug logging regardless of the
+// threshold.
+func WithLevelVar(v *LevelVar) LogOption {
+	return func(o *options) {
+		o.level = v
+	}
+}
+
+// Level returns the current threshold.
+func (v *LevelVar) Level() Severity {
+	if sev := Severity(v.sev.Load()); sev != 0 {
+		return sev
+	}
+	return SeverityInfo
+}
+
+// Set changes the threshold. It returns an error and leaves the threshold
+// unchanged if sev is not one of SeverityDebug, SeverityInfo, SeverityWarn or
+// SeverityError.
+func (v *LevelVar) Set(sev Severity) error {
+	if sev < SeverityDebug || sev > SeverityError {
+		return fmt.Errorf("log: invalid severity %d", sev)
+	}
+	v.sev.Store(int64(sev))
+	return nil
+}
+
+// String returns the name of the current threshold.
+func (v *LevelVar) String() string {
+	return v.Level().String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v *LevelVar) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts the severity
+// names returned by Severity.String, for example "warn".
+func (v *LevelVar) UnmarshalText(text []byte) error {
+	for _, sev := range []Severity{SeverityDebug, SeverityInfo, SeverityWarn, SeverityError} {
+		if string(text) == sev.String() {
+			return v.Set(sev)
+		}
+	}
+	return fmt.Errorf("log: invalid severity %q", text)
+}
+
+// minSeverity returns the minimum severity of the entries logged with o.
+func (o *options) minSeverity() Severity {
+	if o.debug {
+		return SeverityDebug
+	}
+	if o.level != nil {
+		return o.level.Level()
+	}
+	return SeverityInfo
+}
+
+// enabled returns true if entries with the given severity are logged with the
+// given context.
+func enabled(ctx context.Context, sev Severity) bool {
+	l := loggerFrom(ctx)
+	if l == nil {
+		return sev >= SeverityInfo
+	}
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return sev >= l.options.minSeverity()
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelVar(t *testing.T) {
+	var v LevelVar
+	assert.Equal(t, SeverityInfo, v.Level())
+	assert.Equal(t, "info", v.String())
+
+	v.Set(SeverityWarn)
+	assert.Equal(t, SeverityWarn, v.Level())
+	b, err := v.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "warn", string(b))
+
+	assert.EqualError(t, v.Set(Severity(0)), "log: invalid severity 0")
+	assert.EqualError(t, v.Set(SeverityError+1), "log: invalid severity 5")
+	assert.Equal(t, SeverityWarn, v.Level())
+
+	require.NoError(t, v.UnmarshalText([]byte("debug")))
+	assert.Equal(t, SeverityDebug, v.Level())
+	assert.EqualError(t, v.UnmarshalText([]byte("verbose")), `log: invalid severity "verbose"`)
+	assert.Equal(t, SeverityDebug, v.Level())
+}
+
+func TestWithLevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	var level LevelVar
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithLevelVar(&level))
+	FlushAndDisableBuffering(ctx)
+	derived := With(ctx, KV{"k", "v"})
+
+	Debugf(derived, "debug dropped")
+	Printf(derived, "info")
+	level.Set(SeverityWarn)
+	Printf(derived, "info dropped")
+	Warnf(derived, "warn")
+	level.Set(SeverityError)
+	Warnf(ctx, "warn dropped")
+	Errorf(ctx, nil, "error")
+	level.Set(SeverityDebug)
+	assert.True(t, DebugEnabled(derived))
+	Debugf(derived, "debug")
+
+	want := `time=2022-02-22T17:00:00Z level=info k=v msg=info
+time=2022-02-22T17:00:00Z level=warn k=v msg=warn
+time=2022-02-22T17:00:00Z level=error msg=error
+time=2022-02-22T17:00:00Z level=debug k=v msg=debug
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWithLevelVarDebugFlushes(t *testing.T) {
+	var buf bytes.Buffer
+	var level LevelVar
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithLevelVar(&level))
+	Infof(ctx, "buffered")
+	assert.Empty(t, buf.String())
+	assert.False(t, DebugEnabled(ctx))
+
+	level.Set(SeverityDebug)
+	Debugf(ctx, "debug")
+
+	assert.Equal(t, "time=2022-02-22T17:00:00Z level=info msg=buffered\ntime=2022-02-22T17:00:00Z level=debug msg=debug\n", buf.String())
+
+	require.NoError(t, level.Set(SeverityInfo))
+	assert.False(t, DebugEnabled(ctx))
+	buf.Reset()
+	Infof(ctx, "buffered again")
+	assert.Empty(t, buf.String(), "buffering must resume once debug logging is disabled")
+	assert.Len(t, BufferedEntries(ctx), 1)
+
+	assert.True(t, DebugEnabled(Context(ctx, WithDebug())), "WithDebug must take precedence")
+}
+
+func TestWithLevelVarDebugForks(t *testing.T) {
+	var buf bytes.Buffer
+	var level LevelVar
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithLevelVar(&level))
+	require.NoError(t, level.Set(SeverityDebug))
+	Debugf(ctx, "debug")
+	require.NoError(t, level.Set(SeverityInfo))
+	buf.Reset()
+
+	forked := fork(context.Background(), ctx)
+	Infof(forked, "buffered")
+
+	assert.Empty(t, buf.String(), "forks must not inherit debug logging")
+	assert.Len(t, BufferedEntries(forked), 1)
+}
+
+func TestWithLevelVarRequests(t *testing.T) {
+	var buf bytes.Buffer
+	var level LevelVar
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithLevelVar(&level))
+	var handler http.Handler = http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		Warnf(req.Context(), "handled")
+		FlushAndDisableBuffering(req.Context())
+	})
+	handler = HTTP(ctx, WithDisableRequestID())(handler)
+
+	level.Set(SeverityWarn)
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "time=2022-02-22T17:00:00Z level=warn msg=handled\n", buf.String(), "request start and end entries must be filtered")
+}
@@ -1,30 +1,41 @@
 package log
 
 import (
-	"bytes"
 	"context"
-	"errors"
 	"fmt"
-	"io"
 	"os"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
-	// Log entry
+	// Entry is a log entry. The values of the key/value pairs logged with
+	// the typed field constructors (String, Int64, etc.) are Field values,
+	// use Field.Value to retrieve the underlying values.
 	Entry struct {
 		Time     time.Time
 		Severity Severity
 		KeyVals  kvList
+
+		// spanContext is the span context of the logging context if any.
+		spanContext trace.SpanContext
+		// buffered is true if the entry was buffered before being written.
+		buffered bool
 	}
 
 	// Logger implementation
 	logger struct {
 		options *options
-		lock    sync.Mutex
 		keyvals kvList
-		entries []*Entry
+		groups  []group
+		// parent is the logger that owns the buffer shared with the loggers
+		// derived from it, nil if this logger owns its buffer. The fields
+		// below are only used by the loggers that own their buffer.
+		parent  *logger
+		lock    sync.Mutex
+		buffer  buffer
 		flushed bool
 	}
 
@@ -46,6 +57,7 @@ const (
 var (
 	timeNow   = time.Now
 	timeSince = time.Since
+	afterFunc = time.AfterFunc
 	osExit    = os.Exit
 )
 
@@ -106,124 +118,333 @@ func Warnf(ctx context.Context, format string, v ...any) {
 
 // Error flushes the log buffer and disables buffering if not already disabled.
 // Error then sets the ErrorMessageKey (default "err") key with the given error
-// and writes the key/value pairs to the log output.
+// and writes the key/value pairs to the log output. Use WithErrorChain,
+// WithErrorStack, WithGoaErrorFields and WithGRPCErrorDetails to log more
+// details about the error.
 func Error(ctx context.Context, err error, keyvals ...Fielder) {
-	FlushAndDisableBuffering(ctx)
-	if err != nil {
-		kvs := make([]Fielder, len(keyvals)+1)
-		copy(kvs[1:], keyvals)
-		kvs[0] = KV{ErrorMessageKey, err.Error()}
-		keyvals = kvs
-	}
-	log(ctx, SeverityError, true, keyvals)
+	logError(ctx, err, keyvals)
 }
 
 // Errorf sets the key MessageKey (default "msg") and calls Error. Arguments
 // are handled in the manner of fmt.Printf.
 func Errorf(ctx context.Context, err error, format string, v ...any) {
-	Error(ctx, err, KV{MessageKey, fmt.Sprintf(format, v...)})
+	logError(ctx, err, []Fielder{KV{MessageKey, fmt.Sprintf(format, v...)}})
 }
 
 // Fatal is equivalent to Error followed by a call to os.Exit(1)
 func Fatal(ctx context.Context, err error, keyvals ...Fielder) {
-	Error(ctx, err, keyvals...)
+	logError(ctx, err, keyvals)
 	osExit(1)
 }
 
 // Fatalf is equivalent to Errorf followed by a call to os.Exit(1)
 func Fatalf(ctx context.Context, err error, format string, v ...any) {
-	Fatal(ctx, err, KV{MessageKey, fmt.Sprintf(format, v...)})
+	logError(ctx, err, []Fielder{KV{MessageKey, fmt.Sprintf(format, v...)}})
+	osExit(1)
 }
 
 // With creates a copy of the given log context and appends the given key/value
 // pairs to it. Values must be strings, numbers, booleans, nil or a slice of
-// these types.
+// these types. The key/value pairs are added to the last group opened with
+// WithGroup if any.
 func With(ctx context.Context, keyvals ...Fielder) context.Context {
-	v := ctx.Value(ctxLogger)
-	if v == nil {
+	return derive(ctx, func(l *logger) {
+		if len(l.groups) > 0 {
+			l.groups = withGrouped(l.groups, keyvals)
+			return
+		}
+		l.keyvals = l.keyvals.merge(keyvals)
+	})
+}
+
+// derive creates a copy of the logger of the given log context, applies fn to
+// it and returns a context containing the copy. The copy shares the buffer of
+// the original logger so that deriving a context does not copy the buffered
+// entries.
+func derive(ctx context.Context, fn func(*logger)) context.Context {
+	l := loggerFrom(ctx)
+	if l == nil {
 		return ctx
 	}
-	l := v.(*logger)
-	l.lock.Lock()
-	defer l.lock.Unlock()
-	newLogger := logger{
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	newLogger := &logger{
 		options: l.options,
-		entries: l.entries,
-		keyvals: l.keyvals.merge(keyvals),
-		flushed: l.flushed,
+		keyvals: l.keyvals,
+		groups:  l.groups,
+		parent:  o,
 	}
+	fn(newLogger)
 	if l.options.disableBuffering != nil && l.options.disableBuffering(ctx) {
 		l.flush()
+	}
+
+	return context.WithValue(ctx, ctxLogger, newLogger)
+}
+
+// fork returns a copy of parentCtx that contains a copy of the logger of
+// logCtx with its own empty buffer. The middlewares use fork to create the
+// request contexts so that the entries buffered while handling a request are
+// flushed or discarded independently of the other requests. fork returns
+// parentCtx if logCtx does not contain a logger.
+func fork(parentCtx, logCtx context.Context) context.Context {
+	l, ok := logCtx.Value(ctxLogger).(*logger)
+	if !ok {
+		return parentCtx
+	}
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	newLogger := &logger{
+		options: l.options,
+		keyvals: l.keyvals,
+		groups:  l.groups,
+		flushed: o.flushed,
+	}
+	if l.options.disableBuffering != nil && l.options.disableBuffering(parentCtx) {
 		newLogger.flushed = true
-	} else {
-		newLogger.entries = make([]*Entry, len(l.entries))
-		copy(newLogger.entries, l.entries)
 	}
+	return context.WithValue(parentCtx, ctxLogger, newLogger)
+}
+
+// owner returns the logger that owns the buffer used by l.
+func (l *logger) owner() *logger {
+	if l.parent != nil {
+		return l.parent
+	}
+	return l
+}
 
-	return context.WithValue(ctx, ctxLogger, &newLogger)
+// BufferedEntries returns the log entries buffered in the given context that
+// have not been written yet, oldest first. It returns nil if ctx does not
+// contain a logger or if buffering is disabled.
+func BufferedEntries(ctx context.Context) []*Entry {
+	l, ok := ctx.Value(ctxLogger).(*logger)
+	if !ok {
+		return nil
+	}
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.flushed {
+		return nil
+	}
+	list := o.buffer.list()
+	if len(list) == 0 {
+		return nil
+	}
+	res := make([]*Entry, len(list))
+	copy(res, list)
+	return res
 }
 
 // FlushAndDisableBuffering flushes the log entries to the writer and stops
-// buffering the given context.
+// buffering the given context. The buffer is shared by the contexts derived
+// from the same log context with With or WithGroup so that buffering stops
+// for all of them.
 func FlushAndDisableBuffering(ctx context.Context) {
 	v := ctx.Value(ctxLogger)
 	if v == nil {
 		return // do nothing if context isn't initialized
 	}
 	l := v.(*logger)
-	l.lock.Lock()
-	defer l.lock.Unlock()
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
 	l.flush()
 }
 
+// discard drops the log entries buffered in the given context.
+func discard(ctx context.Context) {
+	v := ctx.Value(ctxLogger)
+	if v == nil {
+		return
+	}
+	o := v.(*logger).owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.options.metrics.discard(o.buffer.len())
+	o.buffer.reset()
+}
+
+// flushOrDiscard flushes the log entries buffered in the given context if
+// flush is true and discards them otherwise.
+func flushOrDiscard(ctx context.Context, flush bool) {
+	if flush {
+		FlushAndDisableBuffering(ctx)
+		return
+	}
+	discard(ctx)
+}
+
 func (l *logger) writeEntry(e *Entry) {
-	for _, out := range l.options.outputs {
-		out.Writer.Write(out.Format(e)) // nolint: errcheck
+	l.options.metrics.written(e.Severity)
+	for i, out := range l.options.outputs {
+		if !out.accepts(e) {
+			continue
+		}
+		var err error
+		if out.Handle != nil {
+			err = out.Handle(e)
+		} else {
+			_, err = out.Writer.Write(out.Format(e))
+		}
+		if err != nil {
+			l.options.metrics.writeError(i)
+		}
 	}
 }
 
+// accepts returns true if e must be written to out.
+func (out Output) accepts(e *Entry) bool {
+	if e.Severity < out.MinSeverity {
+		return false
+	}
+	return out.Filter == nil || out.Filter(e)
+}
+
+// flush writes the entries of the buffer shared by l and disables buffering.
+// The lock of the logger owning the buffer must be held.
 func (l *logger) flush() {
-	if l.flushed {
+	o := l.owner()
+	if o.flushed {
 		return
 	}
-	for _, e := range l.entries {
+	l.writeBuffer()
+	o.flushed = true
+}
+
+// writeBuffer writes and removes the entries of the buffer shared by l. The
+// lock of the logger owning the buffer must be held.
+func (l *logger) writeBuffer() {
+	o := l.owner()
+	head, dropped, tail := o.buffer.contents()
+	for _, e := range head {
+		l.writeEntry(e)
+	}
+	if dropped > 0 {
+		l.writeEntry(l.droppedEntry(dropped))
+	}
+	for _, e := range tail {
 		l.writeEntry(e)
 	}
-	l.entries = nil // free up memory
-	l.flushed = true
+	o.buffer.reset() // free up memory
+}
+
+// droppedEntry returns the entry written in place of entries dropped from a
+// bounded buffer.
+func (l *logger) droppedEntry(dropped int) *Entry {
+	keyvals := make(kvList, 0, len(l.options.keyvals)+len(l.keyvals)+2)
+	keyvals = append(keyvals, l.options.keyvals...)
+	keyvals = append(keyvals, l.keyvals...)
+	keyvals = append(keyvals,
+		KV{K: MessageKey, V: "dropped buffered log entries"},
+		KV{K: DroppedEntriesKey, V: dropped})
+	if len(l.options.redactors) > 0 {
+		keyvals = redact(keyvals, l.options.redactors)
+	}
+	return &Entry{Time: timeNow().UTC(), Severity: SeverityWarn, KeyVals: keyvals}
 }
 
 func log(ctx context.Context, sev Severity, buffer bool, fielders []Fielder) {
-	v := ctx.Value(ctxLogger)
-	if v == nil {
-		return // do nothing if context isn't initialized
+	l, ok := ctx.Value(ctxLogger).(*logger)
+	if !ok {
+		if l = uninitialized(); l == nil {
+			return // do nothing if context isn't initialized
+		}
 	}
-	l := v.(*logger)
-	l.lock.Lock()
-	defer l.lock.Unlock()
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
 
-	if !l.options.debug && sev == SeverityDebug {
+	minSev := l.options.minSeverity()
+	if sev < minSev {
 		return
 	}
-	if l.options.debug && !l.flushed {
-		l.flush()
+	// Debug logging disables buffering for as long as it is enabled: the
+	// buffered entries are written and buffering resumes once the threshold
+	// is raised again.
+	debug := minSev == SeverityDebug
+	if debug && !o.flushed {
+		l.writeBuffer()
 	}
 
-	var keyvals kvList
-	keyvals = keyvals.merge(fielders)
-	keyvals = append(l.keyvals, keyvals...)
-	keyvals = append(l.options.keyvals, keyvals...)
+	keyvals := make(kvList, 0, len(l.options.keyvals)+len(l.keyvals)+len(fielders))
+	keyvals = append(keyvals, l.options.keyvals...)
+	keyvals = append(keyvals, l.keyvals...)
+	if len(l.groups) > 0 {
+		var grouped kvList
+		for _, kv := range kvList(nil).merge(fielders) {
+			if ungroupedKey(kv.K) {
+				keyvals = append(keyvals, kv)
+			} else {
+				grouped = append(grouped, kv)
+			}
+		}
+		if kv, ok := nestGroups(l.groups, grouped); ok {
+			keyvals = append(keyvals, kv)
+		}
+	} else {
+		for _, fielder := range fielders {
+			switch f := fielder.(type) {
+			case KV:
+				keyvals = append(keyvals, f)
+			case Field:
+				// fielder holds the boxed field, storing it does not
+				// allocate.
+				keyvals = append(keyvals, KV{K: f.K, V: fielder})
+			default:
+				keyvals = append(keyvals, f.LogFields()...)
+			}
+		}
+	}
 	for _, fn := range l.options.kvfuncs {
 		keyvals = append(keyvals, fn(ctx)...)
 	}
-	truncate(keyvals, l.options.maxsize)
+	if len(l.options.redactors) > 0 {
+		keyvals = redact(keyvals, l.options.redactors)
+	}
+	keyvals = l.options.truncation.truncate(keyvals)
 
-	e := &Entry{timeNow().UTC(), sev, keyvals}
-	if l.flushed || !buffer {
+	e := &Entry{
+		Time:        timeNow().UTC(),
+		Severity:    sev,
+		KeyVals:     keyvals,
+		spanContext: trace.SpanContextFromContext(ctx),
+	}
+	if l.options.spanEvents {
+		addSpanEvent(ctx, e)
+	}
+	if o.flushed || debug || !buffer {
+		if rl := l.options.rateLimiter; rl != nil {
+			ok, summaries := rl.allow(e, l)
+			for _, s := range summaries {
+				l.writeEntry(s)
+			}
+			if !ok {
+				return
+			}
+		}
 		l.writeEntry(e)
 		return
 	}
-	l.entries = append(l.entries, e)
+	e.buffered = true
+	if o.buffer.add(e, l.options.keepFirstBuffered, l.options.maxBuffered) {
+		l.options.metrics.discard(1)
+	}
+}
+
+// SpanContext returns the span context of the context used to create the
+// entry. The returned span context is invalid if the context was not traced.
+func (e *Entry) SpanContext() trace.SpanContext {
+	return e.spanContext
+}
+
+// Buffered returns true if the entry was buffered before being written, false
+// if it was written as soon as it was logged.
+func (e *Entry) Buffered() bool {
+	return e.buffered
 }
 
 // String returns a string representation of the log severity.
@@ -274,68 +495,3 @@ func (l Severity) Color() string {
 		return ""
 	}
 }
-
-const truncationSuffix = " ... <clue/log.truncated>"
-
-var errTruncated = errors.New("truncated value")
-
-// truncate makes sure that all string values in keyvals are no longer than
-// maxsize and that all slice values are truncated to maxsize.
-//
-// Note: This could get very complicated very quickly (there could be different
-// max values for strings and slices, it could compute total size for slices vs.
-// size for each element, could recurse further etc.) - the point is to protect
-// against obvious mistakes - not to implement a bullet-proof solution.
-func truncate(keyvals []KV, maxsize int) {
-	if len(keyvals) > maxsize {
-		keyvals = keyvals[:maxsize]
-		keyvals = append(keyvals, KV{"log", truncationSuffix})
-	}
-	for i, kv := range keyvals {
-		switch kv.V.(type) {
-		case int, int8, int16, int32, int64:
-			continue
-		case uint, uint8, uint16, uint32, uint64:
-			continue
-		case float32, float64:
-			continue
-		case bool:
-			continue
-		case nil:
-			continue
-		default:
-			var buf bytes.Buffer
-			_, err := fmt.Fprintf(newLimitWriter(&buf, maxsize), "%v", kv.V)
-			if errors.Is(err, errTruncated) {
-				fmt.Fprint(&buf, truncationSuffix)
-				keyvals[i] = KV{K: kv.K, V: buf.String()}
-			}
-		}
-	}
-}
-
-type limitWriter struct {
-	io.Writer
-	max int
-	n   int
-}
-
-func newLimitWriter(w io.Writer, max int) io.Writer {
-	return &limitWriter{
-		Writer: w,
-		max:    max,
-	}
-}
-
-func (lw *limitWriter) Write(b []byte) (int, error) {
-	newLen := lw.n + len(b)
-	if newLen > lw.max {
-		oldN := lw.n
-		b = b[:lw.max-lw.n]
-		lw.Writer.Write(b) // nolint: errcheck
-		lw.n = lw.max
-		return lw.max - oldN, errTruncated
-	}
-	lw.n = newLen
-	return lw.Writer.Write(b)
-}
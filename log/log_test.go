@@ -176,6 +176,50 @@ func TestMultipleOutputs(t *testing.T) {
 	assert.Equal(t, "two", b2.String())
 }
 
+func TestOutputFilters(t *testing.T) {
+	var all, warn, filtered bytes.Buffer
+	noHealth := func(e *Entry) bool {
+		for _, kv := range e.KeyVals {
+			if kv.K == HTTPURLKey && kv.V == "/healthz" {
+				return false
+			}
+		}
+		return true
+	}
+	ctx := Context(context.Background(), WithOutputs(
+		Output{Writer: &all, Format: testFormat},
+		Output{Writer: &warn, Format: testFormat, MinSeverity: SeverityWarn},
+		Output{Writer: &filtered, Format: testFormat, Filter: noHealth},
+	), WithDebug())
+
+	Debugf(ctx, "debug")
+	Infof(ctx, "info")
+	Print(ctx, KV{HTTPURLKey, "/healthz"})
+	Warnf(ctx, "warn")
+	Errorf(ctx, nil, "error")
+
+	assert.Equal(t, "debuginfo/healthzwarnerror", all.String())
+	assert.Equal(t, "warnerror", warn.String())
+	assert.Equal(t, "debuginfowarnerror", filtered.String())
+}
+
+func TestOutputFiltersBuffered(t *testing.T) {
+	var all, errs bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(
+		Output{Writer: &all, Format: testFormat},
+		Output{Writer: &errs, Format: testFormat, MinSeverity: SeverityError},
+	))
+
+	Infof(ctx, "info")
+	Warnf(ctx, "warn")
+	assert.Empty(t, all.String())
+	assert.Empty(t, errs.String())
+
+	Errorf(ctx, nil, "error")
+	assert.Equal(t, "infowarnerror", all.String())
+	assert.Equal(t, "error", errs.String())
+}
+
 type ctxTestKey int
 
 const disableBufferingKey ctxTestKey = iota + 1
@@ -375,18 +419,17 @@ func TestChaining(t *testing.T) {
 	Info(ctx1, KV{"msg", "msg1"})
 	Info(ctx2, KV{"msg", "msg2"})
 
-	if len(entries(ctx1)) != 1 {
-		t.Fatalf("got %d buffered entries, want 1", len(entries(ctx1)))
+	if len(entries(ctx1)) != 2 {
+		t.Fatalf("got %d buffered entries, want 2", len(entries(ctx1)))
 	}
-	e := (entries(ctx1))[0]
+	if len(entries(ctx2)) != 2 {
+		t.Fatalf("got %d buffered entries, want 2", len(entries(ctx2)))
+	}
+	e := (entries(ctx2))[0]
 	if len(e.KeyVals) != 1 {
 		t.Errorf("got %d keyvals, want 1", len(e.KeyVals))
 	}
-
-	if len(entries(ctx2)) != 1 {
-		t.Fatalf("got %d buffered entries, want 1", len(entries(ctx2)))
-	}
-	e = (entries(ctx2))[0]
+	e = (entries(ctx1))[1]
 	if len(e.KeyVals) != 2 {
 		t.Errorf("got %d keyvals, want 2", len(e.KeyVals))
 	}
@@ -395,6 +438,52 @@ func TestChaining(t *testing.T) {
 	}
 }
 
+func TestChainingFlush(t *testing.T) {
+	var buf bytes.Buffer
+	ctx1 := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatText}))
+	ctx2 := With(ctx1, KV{"k", "v"})
+	ctx3 := With(ctx2, KV{"k2", "v2"})
+	Info(ctx1, KV{"msg", "msg1"})
+	Info(ctx3, KV{"msg", "msg3"})
+
+	FlushAndDisableBuffering(ctx3)
+	FlushAndDisableBuffering(ctx2)
+	FlushAndDisableBuffering(ctx1)
+	Info(ctx2, KV{"msg", "msg2"})
+
+	want := "time=2022-02-22T17:00:00Z level=info msg=msg1\n" +
+		"time=2022-02-22T17:00:00Z level=info k=v k2=v2 msg=msg3\n" +
+		"time=2022-02-22T17:00:00Z level=info k=v msg=msg2\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFork(t *testing.T) {
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: nopWriter{}, Format: FormatText}))
+	ctx = With(ctx, KV{"k", "v"})
+	Info(ctx, KV{"msg", "parent"})
+	ctx1 := fork(context.Background(), ctx)
+	ctx2 := fork(context.Background(), ctx)
+	Info(ctx1, KV{"msg", "child"})
+
+	assert.Len(t, entries(ctx), 1)
+	assert.Len(t, entries(ctx2), 0)
+	require.Len(t, entries(ctx1), 1)
+	assert.Equal(t, kvList{{"k", "v"}, {"msg", "child"}}, entries(ctx1)[0].KeyVals)
+
+	flushed := func(ctx context.Context) bool { return ctx.Value(ctxLogger).(*logger).flushed }
+	assert.False(t, flushed(ctx2))
+	FlushAndDisableBuffering(ctx)
+	assert.True(t, flushed(fork(context.Background(), ctx)), "forked loggers must inherit disabled buffering")
+	type key struct{}
+	disable := WithDisableBuffering(func(ctx context.Context) bool { return ctx.Value(key{}) != nil })
+	parent := context.WithValue(context.Background(), key{}, true)
+	assert.True(t, flushed(fork(parent, Context(context.Background(), disable))))
+	bg := context.Background()
+	assert.Equal(t, bg, fork(bg, bg))
+}
+
 func TestNoLogging(t *testing.T) {
 	defer func() {
 		if err := recover(); err != nil {
@@ -434,9 +523,9 @@ func TestMaxSize(t *testing.T) {
 	}{
 		{"short message", []KV{msg}, len(txt)},
 		{"long message", []KV{toolong}, maxtruncated},
-		{"too many elements in value", []KV{{"key", toomany}}, maxtruncated},
-		{"too many too long elements in value", toomanytoolong, maxtruncated*maxsize + len(",log:"+truncationSuffix)},
-		{"too many too long elements in []interface{} value", toomanyi, maxtruncated*maxsize + len(",log:"+truncationSuffix)},
+		{"too many elements in value", []KV{{"key", toomany}}, len(txt) * maxsize},
+		{"too many too long elements in value", toomanytoolong, maxtruncated * maxsize},
+		{"too many too long elements in []interface{} value", toomanyi, maxtruncated * maxsize},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -446,6 +535,9 @@ func TestMaxSize(t *testing.T) {
 			format := func(e *Entry) []byte {
 				var vals string
 				for i := 0; i < len(e.KeyVals); i++ {
+					if e.KeyVals[i].K == TruncatedKeysKey {
+						continue
+					}
 					if sv, ok := e.KeyVals[i].V.([]string); ok {
 						vals += strings.Join(sv, "")
 					} else if sv, ok := e.KeyVals[i].V.([]any); ok {
@@ -486,7 +578,7 @@ func TestMaxSize(t *testing.T) {
 		)
 		Print(ctx, KV{"truncated", "it is too long"})
 
-		want := "time=2022-01-09T20:29:45Z level=info truncated=\"it is ... <clue/log.truncated>\"\n"
+		want := "time=2022-01-09T20:29:45Z level=info truncated=\"it is ... <clue/log.truncated>\" log.truncated=[truncated]\n"
 		if got := buf.String(); got != want {
 			t.Errorf("got %q, want %q", got, want)
 		}
@@ -540,6 +632,73 @@ func TestConcurrentLog(t *testing.T) {
 	}
 }
 
+func TestBufferedEntries(t *testing.T) {
+	assert.Nil(t, BufferedEntries(context.Background()))
+	var buf Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: testFormat}))
+	Print(ctx, KV{"msg", printed})
+	Infof(ctx, buffered)
+
+	list := BufferedEntries(ctx)
+	require.Len(t, list, 1)
+	assert.Equal(t, kvList{{MessageKey, buffered}}, list[0].KeyVals)
+	assert.True(t, list[0].Buffered())
+
+	FlushAndDisableBuffering(ctx)
+	assert.Nil(t, BufferedEntries(ctx))
+	assert.Equal(t, printed+buffered, buf.String())
+}
+
+func BenchmarkPrint(b *testing.B) {
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: nopWriter{}, Format: FormatJSON}))
+	FlushAndDisableBuffering(ctx)
+
+	b.Run("KV", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			printKV(ctx)
+		}
+	})
+	b.Run("typed", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			printTyped(ctx)
+		}
+	})
+	assertTypedAllocs(b, ctx)
+}
+
+func BenchmarkWithDeep(b *testing.B) {
+	for _, depth := range []int{10, 100} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				ctx := Context(context.Background())
+				for i := range depth {
+					ctx = With(ctx, KV{"layer", i})
+					Info(ctx, KV{MessageKey, "entering layer"})
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWith(b *testing.B) {
+	ctx := Context(context.Background())
+	for i := range 100 {
+		Info(ctx, KV{"i", i})
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		With(ctx, KV{"key", "val"})
+	}
+}
+
+// nopWriter is an io.Writer that discards all writes.
+type nopWriter struct{}
+
+func (nopWriter) Write(b []byte) (int, error) { return len(b), nil }
+
 func testFormat(e *Entry) []byte {
 	var buf bytes.Buffer
 	for _, kv := range e.KeyVals {
@@ -549,8 +708,8 @@ func testFormat(e *Entry) []byte {
 }
 
 func entries(ctx context.Context) []*Entry {
-	l := ctx.Value(ctxLogger).(*logger)
-	l.lock.Lock()
-	defer l.lock.Unlock()
-	return l.entries
+	o := ctx.Value(ctxLogger).(*logger).owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.buffer.list()
 }
@@ -0,0 +1,293 @@
+// Package logtest provides helpers to capture and assert on the entries
+// logged with the goa.design/clue/log package in unit tests.
+//
+// Usage:
+//
+//	func TestHandler(t *testing.T) {
+//	    t.Parallel()
+//	    ctx, rec := logtest.Context(t)
+//	    handler(ctx)
+//	    rec.AssertLogged(logtest.Message("user created"), logtest.KeyValue("user", "joe"))
+//	    rec.AssertNotLogged(logtest.Severity(log.SeverityError))
+//	}
+package logtest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"goa.design/clue/log"
+)
+
+type (
+	// Recorder records the log entries written to its output in memory. It
+	// is safe for concurrent use. Each test should use its own recorder so
+	// that tests can run in parallel.
+	Recorder struct {
+		t       testing.TB
+		lock    sync.Mutex
+		entries []*log.Entry
+	}
+
+	// Predicate is a function that returns true if the given entry matches.
+	Predicate func(e *log.Entry) bool
+)
+
+// Context returns a log context that writes its entries to a new recorder
+// together with the recorder. The options are applied after the recorder
+// output is configured so that they may override it. The recorded entries are
+// logged with t.Log if the test fails.
+func Context(t testing.TB, opts ...log.LogOption) (context.Context, *Recorder) {
+	t.Helper()
+	rec := NewRecorder(t)
+	opts = append([]log.LogOption{log.WithOutputs(rec.Output())}, opts...)
+	ctx := log.Context(context.Background(), opts...)
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+		for _, e := range rec.Entries() {
+			t.Log(strings.TrimSuffix(string(log.FormatText(e)), "\n"))
+		}
+	})
+	return ctx, rec
+}
+
+// NewRecorder returns a new recorder that reports assertion failures to t.
+func NewRecorder(t testing.TB) *Recorder {
+	return &Recorder{t: t}
+}
+
+// Output returns a log output that records the entries written to it.
+func (r *Recorder) Output() log.Output {
+	return log.Output{Handle: r.record}
+}
+
+// Entries returns the recorded entries in the order they were written.
+func (r *Recorder) Entries() []*log.Entry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	res := make([]*log.Entry, len(r.entries))
+	copy(res, r.entries)
+	return res
+}
+
+// Filter returns the recorded entries that match all the given predicates in
+// the order they were written.
+func (r *Recorder) Filter(preds ...Predicate) []*log.Entry {
+	return filter(r.Entries(), preds)
+}
+
+// Reset discards the recorded entries.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.entries = nil
+}
+
+// AssertLogged reports an error if no recorded entry matches all the given
+// predicates. It returns the first matching entry, nil if there is none.
+func (r *Recorder) AssertLogged(preds ...Predicate) *log.Entry {
+	r.t.Helper()
+	matches := r.Filter(preds...)
+	if len(matches) == 0 {
+		r.t.Errorf("no log entry matches the predicates\nentries:\n%s", format(r.Entries()))
+		return nil
+	}
+	return matches[0]
+}
+
+// AssertNotLogged reports an error if any recorded entry matches all the
+// given predicates.
+func (r *Recorder) AssertNotLogged(preds ...Predicate) {
+	r.t.Helper()
+	if matches := r.Filter(preds...); len(matches) > 0 {
+		r.t.Errorf("unexpected log entries match the predicates:\n%s", format(matches))
+	}
+}
+
+// AssertCount reports an error if the number of recorded entries that match
+// all the given predicates is not n.
+func (r *Recorder) AssertCount(n int, preds ...Predicate) {
+	r.t.Helper()
+	if matches := r.Filter(preds...); len(matches) != n {
+		r.t.Errorf("got %d log entries matching the predicates, want %d:\n%s", len(matches), n, format(matches))
+	}
+}
+
+// AssertOrder reports an error unless the recorded entries contain entries
+// matching each of the given predicates in the given order. Other entries
+// may be written in between.
+func (r *Recorder) AssertOrder(preds ...Predicate) {
+	r.t.Helper()
+	entries := r.Entries()
+	i := 0
+	for _, e := range entries {
+		if i < len(preds) && preds[i](e) {
+			i++
+		}
+	}
+	if i < len(preds) {
+		r.t.Errorf("log entries are not in the expected order: no entry matches predicate %d after the entries matching the previous predicates\nentries:\n%s",
+			i, format(entries))
+	}
+}
+
+// AssertBuffered reports an error if no entry buffered in ctx and not written
+// yet matches all the given predicates. It returns the first matching entry,
+// nil if there is none.
+func (r *Recorder) AssertBuffered(ctx context.Context, preds ...Predicate) *log.Entry {
+	r.t.Helper()
+	buffered := log.BufferedEntries(ctx)
+	matches := filter(buffered, preds)
+	if len(matches) == 0 {
+		r.t.Errorf("no buffered log entry matches the predicates\nbuffered entries:\n%s", format(buffered))
+		return nil
+	}
+	return matches[0]
+}
+
+// record implements the recorder output.
+func (r *Recorder) record(e *log.Entry) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+// Severity returns a predicate that matches entries with the given severity.
+func Severity(sev log.Severity) Predicate {
+	return func(e *log.Entry) bool { return e.Severity == sev }
+}
+
+// Message returns a predicate that matches entries whose message (the value
+// of log.MessageKey) is msg.
+func Message(msg string) Predicate {
+	return func(e *log.Entry) bool {
+		v, ok := Value(e, log.MessageKey)
+		return ok && v == msg
+	}
+}
+
+// MessageContains returns a predicate that matches entries whose message
+// contains substr.
+func MessageContains(substr string) Predicate {
+	return func(e *log.Entry) bool {
+		v, ok := Value(e, log.MessageKey)
+		s, isString := v.(string)
+		return ok && isString && strings.Contains(s, substr)
+	}
+}
+
+// HasKey returns a predicate that matches entries that contain the given key.
+// Keys of grouped values are given using their dotted path, e.g. "db.table".
+func HasKey(key string) Predicate {
+	return func(e *log.Entry) bool {
+		_, ok := Value(e, key)
+		return ok
+	}
+}
+
+// KeyValue returns a predicate that matches entries that contain the given
+// key with the given value. Integers of different types are equal if they
+// have the same value. Keys of grouped values are given using their dotted
+// path, e.g. "db.table".
+func KeyValue(key string, val any) Predicate {
+	return func(e *log.Entry) bool {
+		v, ok := Value(e, key)
+		return ok && equal(v, val)
+	}
+}
+
+// Buffered returns a predicate that matches entries that were buffered
+// before being written.
+func Buffered() Predicate {
+	return func(e *log.Entry) bool { return e.Buffered() }
+}
+
+// NotBuffered returns a predicate that matches entries that were written as
+// soon as they were logged.
+func NotBuffered() Predicate {
+	return func(e *log.Entry) bool { return !e.Buffered() }
+}
+
+// Value returns the value of the first key/value pair of e with the given
+// key. Keys of grouped values are given using their dotted path, e.g.
+// "db.table". The underlying value of typed fields is returned, see
+// log.Field.
+func Value(e *log.Entry, key string) (any, bool) {
+	return lookup(e.KeyVals, key)
+}
+
+// lookup returns the value of key in kvs.
+func lookup(kvs []log.KV, key string) (any, bool) {
+	for _, kv := range kvs {
+		if kv.K == key {
+			if f, ok := kv.V.(log.Field); ok {
+				return f.Value(), true
+			}
+			return kv.V, true
+		}
+		if g, ok := kv.V.([]log.KV); ok && strings.HasPrefix(key, kv.K+".") {
+			if v, ok := lookup(g, key[len(kv.K)+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// equal returns true if the values are deeply equal or if both are integers
+// with the same value.
+func equal(a, b any) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ia, aok := toInt(a)
+	ib, bok := toInt(b)
+	return aok && bok && ia == ib
+}
+
+// toInt returns the value of v as an int64 if it is an integer that fits.
+func toInt(v any) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		return int64(u), u <= 1<<63-1
+	}
+	return 0, false
+}
+
+// filter returns the entries that match all the predicates.
+func filter(entries []*log.Entry, preds []Predicate) []*log.Entry {
+	var res []*log.Entry
+outer:
+	for _, e := range entries {
+		for _, p := range preds {
+			if !p(e) {
+				continue outer
+			}
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+// format returns the text representation of the given entries.
+func format(entries []*log.Entry) string {
+	if len(entries) == 0 {
+		return "  (none)\n"
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString("  ")
+		b.Write(log.FormatText(e))
+	}
+	return b.String()
+}
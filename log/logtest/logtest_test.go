@@ -0,0 +1,138 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"goa.design/clue/log"
+)
+
+// fakeT records the errors reported by the assertions.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+	ctx, rec := Context(t)
+
+	log.Print(ctx, log.KV{K: log.MessageKey, V: "hello"}, log.KV{K: "count", V: 3})
+	log.Infof(ctx, "buffered")
+	log.Errorf(ctx, errors.New("boom"), "failed")
+
+	entries := rec.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "hello", entries[0].KeyVals[0].V)
+	rec.AssertLogged(Message("hello"), KeyValue("count", int64(3)), NotBuffered())
+	rec.AssertLogged(Message("buffered"), Severity(log.SeverityInfo), Buffered())
+	rec.AssertLogged(MessageContains("fail"), Severity(log.SeverityError), KeyValue(log.ErrorMessageKey, "boom"))
+	rec.AssertNotLogged(Severity(log.SeverityDebug))
+	rec.AssertCount(2, Severity(log.SeverityInfo))
+	rec.AssertOrder(Message("hello"), Message("buffered"), Message("failed"))
+	assert.Len(t, rec.Filter(HasKey("count")), 1)
+
+	rec.Reset()
+	assert.Empty(t, rec.Entries())
+}
+
+func TestContextOptions(t *testing.T) {
+	t.Parallel()
+	ctx, rec := Context(t, log.WithDebug())
+
+	log.Debugf(ctx, "debug")
+
+	rec.AssertLogged(Message("debug"), Severity(log.SeverityDebug))
+}
+
+func TestAssertBuffered(t *testing.T) {
+	t.Parallel()
+	ctx, rec := Context(t)
+
+	log.Infof(ctx, "pending")
+
+	rec.AssertNotLogged(Message("pending"))
+	assert.True(t, rec.AssertBuffered(ctx, Message("pending")).Buffered())
+
+	ft := &fakeT{}
+	NewRecorder(ft).AssertBuffered(ctx, Message("other"))
+	require.Len(t, ft.errors, 1)
+	assert.Contains(t, ft.errors[0], "msg=pending")
+
+	log.FlushAndDisableBuffering(ctx)
+	rec.AssertLogged(Message("pending"), Buffered())
+	assert.Empty(t, log.BufferedEntries(ctx))
+}
+
+func TestGroups(t *testing.T) {
+	t.Parallel()
+	ctx, rec := Context(t)
+
+	log.Print(ctx, log.Group("db", log.KV{K: "table", V: "users"}, log.Group("conn", log.KV{K: "id", V: 1})))
+
+	rec.AssertLogged(KeyValue("db.table", "users"), KeyValue("db.conn.id", 1), HasKey("db.conn"))
+	rec.AssertNotLogged(HasKey("db.missing"))
+	rec.AssertNotLogged(HasKey("table"))
+}
+
+func TestAssertionFailures(t *testing.T) {
+	t.Parallel()
+	ft := &fakeT{}
+	rec := NewRecorder(ft)
+	ctx := log.Context(context.Background(), log.WithOutputs(rec.Output()))
+
+	log.Print(ctx, log.KV{K: log.MessageKey, V: "first"})
+	log.Print(ctx, log.KV{K: log.MessageKey, V: "second"})
+
+	assert.Nil(t, rec.AssertLogged(Message("missing")))
+	rec.AssertNotLogged(Message("first"))
+	rec.AssertCount(1, Severity(log.SeverityInfo))
+	rec.AssertOrder(Message("second"), Message("first"))
+	rec.AssertOrder(Message("first"), Message("second"))
+
+	require.Len(t, ft.errors, 4)
+	assert.Contains(t, ft.errors[0], "no log entry matches")
+	assert.Contains(t, ft.errors[0], "msg=first")
+	assert.Contains(t, ft.errors[1], "unexpected log entries")
+	assert.Contains(t, ft.errors[2], "got 2 log entries")
+	assert.Contains(t, ft.errors[3], "not in the expected order")
+}
+
+func TestRecorderConcurrency(t *testing.T) {
+	t.Parallel()
+	ctx, rec := Context(t)
+	log.FlushAndDisableBuffering(ctx)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Print(ctx, log.KV{K: "i", V: i})
+			rec.Filter(HasKey("i"))
+		}()
+	}
+	wg.Wait()
+
+	rec.AssertCount(10, HasKey("i"))
+}
+
+func TestEqual(t *testing.T) {
+	t.Parallel()
+	assert.True(t, equal(1, int64(1)))
+	assert.True(t, equal(uint8(1), 1))
+	assert.True(t, equal([]string{"a"}, []string{"a"}))
+	assert.False(t, equal(1, "1"))
+	assert.False(t, equal(uint64(1<<63), int64(-1<<63)))
+}
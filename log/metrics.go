@@ -0,0 +1,127 @@
+package log
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// logMetrics holds the instruments used to record log volume metrics.
+type logMetrics struct {
+	entries     metric.Int64Counter
+	discarded   metric.Int64Counter
+	writeErrors metric.Int64Counter
+	// severities contains the severity attribute option of each severity
+	// indexed by severity.
+	severities [SeverityError + 1]metric.AddOption
+}
+
+const (
+	// EntriesMetric is the name of the counter recording the number of log
+	// entries written by severity.
+	EntriesMetric = "log.entries"
+	// DiscardedEntriesMetric is the name of the counter recording the number
+	// of buffered log entries that were discarded without being written.
+	DiscardedEntriesMetric = "log.entries.discarded"
+	// WriteErrorsMetric is the name of the counter recording the number of
+	// errors returned by each output.
+	WriteErrorsMetric = "log.write.errors"
+
+	// SeverityAttributeKey is the attribute key used to record the entry
+	// severity.
+	SeverityAttributeKey = "log.severity"
+	// OutputAttributeKey is the attribute key used to record the index of
+	// the output in the list given to WithOutputs.
+	OutputAttributeKey = "log.output"
+)
+
+// WithMeter records metrics about the log entries using the given meter
+// provider:
+//
+//   - log.entries counts the entries written by severity (log.severity
+//     attribute).
+//   - log.entries.discarded counts the buffered entries that were discarded
+//     without being written, either because a bounded buffer was full (see
+//     WithMaxBufferedEntries) or because the request handled by the HTTP
+//     middleware or gRPC server interceptors completed without the buffer
+//     being flushed (see WithFlushIf and WithCallFlushIf). The entries
+//     buffered in contexts that are not created by the middlewares are not
+//     counted as they are never known to be discarded.
+//   - log.write.errors counts the errors returned by the outputs writers and
+//     handlers by output (log.output attribute, the index of the output in the
+//     list given to WithOutputs).
+//
+// If mp is nil the global meter provider is used, that is the meter provider
+// configured via clue.ConfigureOpenTelemetry. The global meter provider may be
+// configured after the log context is created.
+func WithMeter(mp metric.MeterProvider) LogOption {
+	return func(o *options) {
+		if mp == nil {
+			mp = otel.GetMeterProvider()
+		}
+		m, err := newLogMetrics(mp)
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		o.metrics = m
+	}
+}
+
+// newLogMetrics creates the log instruments using the given meter provider.
+func newLogMetrics(mp metric.MeterProvider) (*logMetrics, error) {
+	meter := mp.Meter(InstrumentationName)
+	entries, err := meter.Int64Counter(EntriesMetric,
+		metric.WithDescription("Number of log entries written"),
+		metric.WithUnit("{entry}"))
+	if err != nil {
+		return nil, err
+	}
+	discarded, err := meter.Int64Counter(DiscardedEntriesMetric,
+		metric.WithDescription("Number of buffered log entries discarded without being written"),
+		metric.WithUnit("{entry}"))
+	if err != nil {
+		return nil, err
+	}
+	writeErrors, err := meter.Int64Counter(WriteErrorsMetric,
+		metric.WithDescription("Number of errors returned by log outputs"),
+		metric.WithUnit("{error}"))
+	if err != nil {
+		return nil, err
+	}
+	m := &logMetrics{entries: entries, discarded: discarded, writeErrors: writeErrors}
+	for sev := SeverityDebug; sev <= SeverityError; sev++ {
+		m.severities[sev] = metric.WithAttributeSet(attribute.NewSet(attribute.String(SeverityAttributeKey, sev.String())))
+	}
+	return m, nil
+}
+
+// written records an entry written with the given severity.
+func (m *logMetrics) written(sev Severity) {
+	if m == nil {
+		return
+	}
+	if sev < SeverityDebug || sev > SeverityError {
+		m.entries.Add(context.Background(), 1)
+		return
+	}
+	m.entries.Add(context.Background(), 1, m.severities[sev])
+}
+
+// discard records n discarded entries.
+func (m *logMetrics) discard(n int) {
+	if m == nil || n == 0 {
+		return
+	}
+	m.discarded.Add(context.Background(), int64(n))
+}
+
+// writeError records a write error for the output at index i.
+func (m *logMetrics) writeError(i int) {
+	if m == nil {
+		return
+	}
+	m.writeErrors.Add(context.Background(), 1, metric.WithAttributes(attribute.Int(OutputAttributeKey, i)))
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+)
+
+// failingWriter is an io.Writer that always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestWithMeter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	var buf Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(
+			Output{Writer: &buf, Format: testFormat},
+			Output{Writer: failingWriter{}, Format: testFormat, MinSeverity: SeverityError},
+		),
+		WithMaxBufferedEntries(1),
+		WithMeter(mp))
+
+	Print(ctx, KV{"msg", "one"})
+	Print(ctx, KV{"msg", "two"})
+	Warnf(ctx, "dropped")
+	Warnf(ctx, "buffered")
+	Errorf(ctx, nil, "error")
+	reqCtx := With(ctx)
+	Infof(reqCtx, "written after error")
+
+	other := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: testFormat}), WithMeter(mp))
+	Infof(other, "discarded")
+	discard(other)
+
+	counts := collectCounts(t, reader)
+	assert.Equal(t, map[string]int64{
+		"info":  3,
+		"warn":  2, // buffered entry and dropped entries summary
+		"error": 1,
+	}, counts[EntriesMetric+"/"+SeverityAttributeKey])
+	assert.Equal(t, map[string]int64{"": 2}, counts[DiscardedEntriesMetric+"/"])
+	assert.Equal(t, map[string]int64{"1": 1}, counts[WriteErrorsMetric+"/"+OutputAttributeKey])
+}
+
+func TestWithMeterUnflushedRequests(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: nopWriter{}, Format: testFormat}), WithMeter(mp))
+
+	handler := HTTP(ctx, WithDisableRequestLogging())(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		Infof(req.Context(), "one")
+		Infof(req.Context(), "two")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	unary := UnaryServerInterceptor(ctx, WithDisableCallLogging())
+	_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Test/Unary"}, func(ctx context.Context, _ any) (any, error) {
+		Infof(ctx, "three")
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	counts := collectCounts(t, reader)
+	assert.Equal(t, map[string]int64{"": 3}, counts[DiscardedEntriesMetric+"/"])
+}
+
+func TestWithMeterHandleErrors(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Handle: func(*Entry) error { return errors.New("failed") }}),
+		WithMeter(mp))
+
+	Printf(ctx, "hello")
+
+	counts := collectCounts(t, reader)
+	assert.Equal(t, map[string]int64{"0": 1}, counts[WriteErrorsMetric+"/"+OutputAttributeKey])
+}
+
+// collectCounts returns the sums recorded by the reader indexed by metric name
+// and attribute key then by attribute value.
+func collectCounts(t *testing.T, reader sdkmetric.Reader) map[string]map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	counts := make(map[string]map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		assert.Equal(t, InstrumentationName, sm.Scope.Name)
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok, "metric %s is not an int64 sum", m.Name)
+			for _, dp := range sum.DataPoints {
+				var key, val string
+				if dp.Attributes.Len() > 0 {
+					kv := dp.Attributes.ToSlice()[0]
+					key, val = string(kv.Key), kv.Value.Emit()
+				}
+				name := m.Name + "/" + key
+				if counts[name] == nil {
+					counts[name] = make(map[string]int64)
+				}
+				counts[name][val] += dp.Value
+			}
+		}
+	}
+	return counts
+}
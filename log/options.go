@@ -23,26 +23,51 @@ type (
 	// FormatFunc is a function that formats a log entry.
 	FormatFunc func(e *Entry) []byte
 
+	// HandleFunc is a function that receives structured log entries, for
+	// example to forward them to a different logging API.
+	HandleFunc func(e *Entry) error
+
 	// Output configures where log entries are written and how they are formatted.
 	//
 	// Output is the unit of configuration for "fanout" logging: a single log entry
 	// can be written to multiple outputs, each with its own formatting.
 	//
-	// Writer and Format must be non-nil.
+	// Writer and Format must be non-nil unless Handle is set.
 	Output struct {
 		// Writer receives the formatted log bytes.
 		Writer io.Writer
 		// Format turns a log entry into bytes suitable for Writer.
 		Format FormatFunc
+		// Handle receives the structured log entry. Writer and Format are
+		// not used when Handle is set.
+		Handle HandleFunc
+		// MinSeverity is the minimum severity of the entries written to the
+		// output. The zero value writes entries of all severities.
+		MinSeverity Severity
+		// Filter returns false for entries that must not be written to the
+		// output. Filter is called after the MinSeverity check.
+		Filter func(e *Entry) bool
 	}
 
 	options struct {
 		disableBuffering DisableBufferingFunc
 		debug            bool
+		level            *LevelVar
 		outputs          []Output
 		keyvals          kvList
 		kvfuncs          []func(context.Context) []KV
-		maxsize          int
+		truncation       TruncationPolicy
+
+		maxBuffered       int
+		keepFirstBuffered int
+		redactors         []*redactor
+		rateLimiter       *rateLimiter
+		metrics           *logMetrics
+		spanEvents        bool
+		errorChain        bool
+		errorStack        bool
+		goaErrorFields    bool
+		grpcErrorDetails  bool
 	}
 )
 
@@ -117,6 +142,10 @@ func WithOutputs(outputs ...Output) LogOption {
 			panic("log.WithOutputs: at least one output must be provided")
 		}
 		for i, out := range outputs {
+			if out.Handle != nil {
+				outputs[i] = out
+				continue
+			}
 			if out.Writer == nil {
 				panic("log.WithOutputs: output writer is nil")
 			}
@@ -129,10 +158,39 @@ func WithOutputs(outputs ...Output) LogOption {
 	}
 }
 
-// WithMaxSize sets the maximum size of a single log message or value.
+// WithMaxSize sets the maximum number of keys of a log entry, the maximum
+// length in bytes of string values and the maximum number of elements of slice
+// and map values to n. It does not change the maximum size of entries. Use
+// WithTruncation to set the limits separately.
 func WithMaxSize(n int) LogOption {
 	return func(o *options) {
-		o.maxsize = n
+		o.truncation.MaxKeys = n
+		o.truncation.MaxStringBytes = n
+		o.truncation.MaxSliceElements = n
+	}
+}
+
+// WithMaxBufferedEntries bounds the number of log entries kept in the buffer
+// of each log context. Once the limit is reached the oldest entries are
+// dropped so that only the n most recent entries are kept (see also
+// WithKeepFirstBufferedEntries). When the buffer is flushed, a warning entry
+// with the number of dropped entries under DroppedEntriesKey is written before
+// the most recent entries. A value of 0 (the default) means no limit.
+//
+// The total number of dropped entries is available via
+// DroppedBufferedEntries.
+func WithMaxBufferedEntries(n int) LogOption {
+	return func(o *options) {
+		o.maxBuffered = n
+	}
+}
+
+// WithKeepFirstBufferedEntries keeps the first k log entries of a bounded
+// buffer in addition to the most recent entries. It has no effect unless
+// WithMaxBufferedEntries is also set.
+func WithKeepFirstBufferedEntries(k int) LogOption {
+	return func(o *options) {
+		o.keepFirstBuffered = k
 	}
 }
 
@@ -180,6 +238,10 @@ func defaultOptions() *options {
 	return &options{
 		disableBuffering: IsTracing,
 		outputs:          []Output{{Writer: os.Stdout, Format: format}},
-		maxsize:          DefaultMaxSize,
+		truncation: TruncationPolicy{
+			MaxKeys:          DefaultMaxSize,
+			MaxStringBytes:   DefaultMaxSize,
+			MaxSliceElements: DefaultMaxSize,
+		},
 	}
 }
@@ -21,7 +21,7 @@ func TestDefaultOptions(t *testing.T) {
 		assert.Equal(t, os.Stdout, opts.outputs[0].Writer)
 		assert.Equal(t, fmt.Sprintf("%p", opts.outputs[0].Format), fmt.Sprintf("%p", FormatText))
 	}
-	assert.Equal(t, opts.maxsize, DefaultMaxSize)
+	assert.Equal(t, TruncationPolicy{MaxKeys: DefaultMaxSize, MaxStringBytes: DefaultMaxSize, MaxSliceElements: DefaultMaxSize}, opts.truncation)
 }
 
 func TestDefaultOptionsTerminalFormat(t *testing.T) {
@@ -84,6 +84,16 @@ func TestWithOutputs(t *testing.T) {
 	}
 }
 
+func TestWithOutputsHandle(t *testing.T) {
+	opts := defaultOptions()
+	handle := func(*Entry) error { return nil }
+	WithOutputs(Output{Handle: handle})(opts)
+	if assert.Len(t, opts.outputs, 1) {
+		assert.Nil(t, opts.outputs[0].Writer)
+		assert.Equal(t, fmt.Sprintf("%p", opts.outputs[0].Handle), fmt.Sprintf("%p", handle))
+	}
+}
+
 func TestWithOutputRequiresInitializedOutputs(t *testing.T) {
 	opts := &options{}
 	assert.PanicsWithValue(t, "log.WithOutput: logger outputs not initialized", func() {
@@ -123,8 +133,22 @@ func TestWithOutputsPanics(t *testing.T) {
 
 func TestWithMaxSize(t *testing.T) {
 	opts := defaultOptions()
+	WithTruncation(TruncationPolicy{MaxEntryBytes: 100})(opts)
 	WithMaxSize(10)(opts)
-	assert.Equal(t, opts.maxsize, 10)
+	assert.Equal(t, TruncationPolicy{MaxKeys: 10, MaxStringBytes: 10, MaxSliceElements: 10, MaxEntryBytes: 100}, opts.truncation)
+}
+
+func TestWithMaxBufferedEntries(t *testing.T) {
+	opts := defaultOptions()
+	assert.Zero(t, opts.maxBuffered)
+	WithMaxBufferedEntries(10)(opts)
+	assert.Equal(t, 10, opts.maxBuffered)
+}
+
+func TestWithKeepFirstBufferedEntries(t *testing.T) {
+	opts := defaultOptions()
+	WithKeepFirstBufferedEntries(5)(opts)
+	assert.Equal(t, 5, opts.keepFirstBuffered)
 }
 
 func TestIsTracing(t *testing.T) {
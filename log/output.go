@@ -0,0 +1,194 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the OpenTelemetry logger used by
+// OTelOutput.
+const InstrumentationName = "goa.design/clue/log"
+
+// OTelOutput returns an output that emits log entries using the OpenTelemetry
+// Logs API. Each entry is converted into a log record: the value of the
+// MessageKey key becomes the record body and all other key/value pairs are
+// recorded as attributes. Groups are recorded as map attributes. The trace
+// and span IDs of the context used to log the entry are recorded with the
+// record.
+//
+// Usage:
+//
+//	ctx := log.Context(context.Background(), log.WithOutputs(
+//	    log.Output{Writer: os.Stdout, Format: log.FormatTerminal},
+//	    log.OTelOutput(loggerProvider),
+//	))
+func OTelOutput(lp otellog.LoggerProvider) Output {
+	logger := lp.Logger(InstrumentationName)
+	return Output{Handle: func(e *Entry) error {
+		var rec otellog.Record
+		rec.SetTimestamp(e.Time)
+		rec.SetObservedTimestamp(timeNow().UTC())
+		rec.SetSeverity(otelSeverity(e.Severity))
+		rec.SetSeverityText(e.Severity.String())
+		attrs := make([]otellog.KeyValue, 0, len(e.KeyVals))
+		for _, kv := range e.KeyVals {
+			if kv.K == MessageKey && rec.Body().Empty() {
+				rec.SetBody(otelValue(kv.V))
+				continue
+			}
+			attrs = append(attrs, otellog.KeyValue{Key: kv.K, Value: otelValue(kv.V)})
+		}
+		rec.AddAttributes(attrs...)
+		logger.Emit(entryContext(e), rec)
+		return nil
+	}}
+}
+
+// SlogOutput returns an output that forwards log entries to the given
+// slog.Handler. The value of the MessageKey key becomes the record message and
+// all other key/value pairs are added as record attributes. Groups are added
+// as slog groups.
+func SlogOutput(h slog.Handler) Output {
+	return Output{Handle: func(e *Entry) error {
+		ctx := entryContext(e)
+		level := slogLevel(e.Severity)
+		if !h.Enabled(ctx, level) {
+			return nil
+		}
+		var msg string
+		attrs := make([]slog.Attr, 0, len(e.KeyVals))
+		for _, kv := range e.KeyVals {
+			if kv.K == MessageKey && msg == "" {
+				msg = fmt.Sprint(kv.V)
+				continue
+			}
+			attrs = append(attrs, slogAttr(kv))
+		}
+		rec := slog.NewRecord(e.Time, level, msg, 0)
+		rec.AddAttrs(attrs...)
+		return h.Handle(ctx, rec)
+	}}
+}
+
+// entryContext returns a context that contains the span context of e.
+func entryContext(e *Entry) context.Context {
+	ctx := context.Background()
+	if e.spanContext.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, e.spanContext)
+	}
+	return ctx
+}
+
+// slogAttr converts a key/value pair to a slog attribute.
+func slogAttr(kv KV) slog.Attr {
+	g, ok := kv.V.([]KV)
+	if !ok {
+		return slog.Any(kv.K, plainValue(kv.V))
+	}
+	attrs := make([]slog.Attr, len(g))
+	for i, kv := range g {
+		attrs[i] = slogAttr(kv)
+	}
+	return slog.Attr{Key: kv.K, Value: slog.GroupValue(attrs...)}
+}
+
+// otelSeverity maps the given severity to an OpenTelemetry log severity.
+func otelSeverity(sev Severity) otellog.Severity {
+	switch sev {
+	case SeverityDebug:
+		return otellog.SeverityDebug
+	case SeverityInfo:
+		return otellog.SeverityInfo
+	case SeverityWarn:
+		return otellog.SeverityWarn
+	case SeverityError:
+		return otellog.SeverityError
+	default:
+		return otellog.SeverityUndefined
+	}
+}
+
+// slogLevel maps the given severity to a slog level.
+func slogLevel(sev Severity) slog.Level {
+	switch sev {
+	case SeverityDebug:
+		return slog.LevelDebug
+	case SeverityWarn:
+		return slog.LevelWarn
+	case SeverityError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// otelValue converts a log value to an OpenTelemetry log value.
+func otelValue(v any) otellog.Value {
+	switch v := plainValue(v).(type) {
+	case nil:
+		return otellog.Value{}
+	case string:
+		return otellog.StringValue(v)
+	case bool:
+		return otellog.BoolValue(v)
+	case int:
+		return otellog.IntValue(v)
+	case int8:
+		return otellog.Int64Value(int64(v))
+	case int16:
+		return otellog.Int64Value(int64(v))
+	case int32:
+		return otellog.Int64Value(int64(v))
+	case int64:
+		return otellog.Int64Value(v)
+	case uint:
+		return otellog.Int64Value(int64(v))
+	case uint8:
+		return otellog.Int64Value(int64(v))
+	case uint16:
+		return otellog.Int64Value(int64(v))
+	case uint32:
+		return otellog.Int64Value(int64(v))
+	case uint64:
+		return otellog.Int64Value(int64(v))
+	case float32:
+		return otellog.Float64Value(float64(v))
+	case float64:
+		return otellog.Float64Value(v)
+	case []byte:
+		return otellog.BytesValue(v)
+	case time.Duration:
+		return otellog.StringValue(v.String())
+	case time.Time:
+		return otellog.StringValue(v.Format(time.RFC3339Nano))
+	case error:
+		return otellog.StringValue(v.Error())
+	case fmt.Stringer:
+		return otellog.StringValue(v.String())
+	case []any:
+		vals := make([]otellog.Value, len(v))
+		for i, e := range v {
+			vals[i] = otelValue(e)
+		}
+		return otellog.SliceValue(vals...)
+	case []string:
+		vals := make([]otellog.Value, len(v))
+		for i, e := range v {
+			vals[i] = otellog.StringValue(e)
+		}
+		return otellog.SliceValue(vals...)
+	case []KV:
+		kvs := make([]otellog.KeyValue, len(v))
+		for i, kv := range v {
+			kvs[i] = otellog.KeyValue{Key: kv.K, Value: otelValue(kv.V)}
+		}
+		return otellog.MapValue(kvs...)
+	default:
+		return otellog.StringValue(fmt.Sprintf("%v", v))
+	}
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/embedded"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	testLoggerProvider struct {
+		embedded.LoggerProvider
+		logger *testOTelLogger
+	}
+
+	testOTelLogger struct {
+		embedded.Logger
+		lock    sync.Mutex
+		name    string
+		records []testOTelRecord
+	}
+
+	testOTelRecord struct {
+		ctx    context.Context
+		record otellog.Record
+	}
+)
+
+func TestOTelOutput(t *testing.T) {
+	lp := &testLoggerProvider{logger: &testOTelLogger{}}
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	spanID := trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8}
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+	ctx = Context(ctx, WithOutputs(OTelOutput(lp)))
+
+	Info(ctx, KV{"msg", "hello"}, KV{"str", "val"}, KV{"int", 1}, KV{"slice", []any{"a", 2}}, KV{"dur", time.Second},
+		Group("db", KV{"table", "users"}, Group("conn", KV{"id", 1})))
+	Errorf(ctx, assert.AnError, "failed")
+
+	assert.Equal(t, InstrumentationName, lp.logger.name)
+	require.Len(t, lp.logger.records, 2)
+
+	info := lp.logger.records[0]
+	assert.Equal(t, spanCtx, trace.SpanContextFromContext(info.ctx))
+	assert.Equal(t, time.Date(2022, time.February, 22, 17, 0, 0, 0, time.UTC), info.record.Timestamp())
+	assert.Equal(t, otellog.SeverityInfo, info.record.Severity())
+	assert.Equal(t, "info", info.record.SeverityText())
+	assert.Equal(t, "hello", info.record.Body().AsString())
+	var attrs []otellog.KeyValue
+	info.record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs = append(attrs, kv)
+		return true
+	})
+	want := []otellog.KeyValue{
+		otellog.String("str", "val"),
+		otellog.Int("int", 1),
+		otellog.Slice("slice", otellog.StringValue("a"), otellog.IntValue(2)),
+		otellog.String("dur", "1s"),
+		otellog.Map("db", otellog.String("table", "users"), otellog.Map("conn", otellog.Int("id", 1))),
+	}
+	require.Len(t, attrs, len(want))
+	for i, kv := range want {
+		assert.True(t, kv.Equal(attrs[i]), "attribute %d: got %v, want %v", i, attrs[i], kv)
+	}
+
+	errRec := lp.logger.records[1]
+	assert.Equal(t, otellog.SeverityError, errRec.record.Severity())
+	assert.Equal(t, "failed", errRec.record.Body().AsString())
+}
+
+func TestSlogOutput(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	ctx := Context(context.Background(), WithOutputs(SlogOutput(h)), WithDebug())
+
+	Debugf(ctx, "ignored by handler")
+	Print(ctx, KV{"msg", "hello"}, KV{"key", "val"})
+	Warnf(ctx, "careful")
+
+	want := "time=2022-02-22T17:00:00.000Z level=INFO msg=hello key=val\n" +
+		"time=2022-02-22T17:00:00.000Z level=WARN msg=careful\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestSlogOutputGroups(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, nil)
+	ctx := Context(context.Background(), WithOutputs(SlogOutput(h)))
+
+	Print(ctx, KV{"msg", "query"}, Group("db", KV{"table", "users"}, Group("conn", KV{"id", 1})))
+
+	want := `{"time":"2022-02-22T17:00:00Z","level":"INFO","msg":"query","db":{"table":"users","conn":{"id":1}}}` + "\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestOTelSeverity(t *testing.T) {
+	assert.Equal(t, otellog.SeverityDebug, otelSeverity(SeverityDebug))
+	assert.Equal(t, otellog.SeverityInfo, otelSeverity(SeverityInfo))
+	assert.Equal(t, otellog.SeverityWarn, otelSeverity(SeverityWarn))
+	assert.Equal(t, otellog.SeverityError, otelSeverity(SeverityError))
+	assert.Equal(t, otellog.SeverityUndefined, otelSeverity(Severity(0)))
+}
+
+func (p *testLoggerProvider) Logger(name string, _ ...otellog.LoggerOption) otellog.Logger {
+	p.logger.name = name
+	return p.logger
+}
+
+func (l *testOTelLogger) Emit(ctx context.Context, rec otellog.Record) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.records = append(l.records, testOTelRecord{ctx: ctx, record: rec.Clone()})
+}
+
+func (l *testOTelLogger) Enabled(context.Context, otellog.EnabledParameters) bool {
+	return true
+}
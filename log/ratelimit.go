@@ -0,0 +1,215 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// rateLimiter limits the number of similar entries written per interval.
+	// It is shared by all the loggers derived from the same log context.
+	rateLimiter struct {
+		limit    int
+		interval time.Duration
+		keys     []string
+
+		lock      sync.Mutex
+		buckets   map[string]*rateBucket
+		nextSweep time.Time
+		// flushing is true if a call to flush is scheduled.
+		flushing bool
+		// logger is the logger that last suppressed an entry, it is used
+		// to write the summaries in flush.
+		logger *logger
+		// keyvals are the key/value pairs of logger added to the summaries
+		// written in flush.
+		keyvals []KV
+	}
+
+	// rateBucket counts the entries with the same rate limiting key during
+	// the current interval.
+	rateBucket struct {
+		start      time.Time
+		count      int
+		suppressed int
+		severity   Severity
+		msg        any
+		keyvals    []KV
+	}
+)
+
+// WithRateLimit limits the number of similar log entries written per interval.
+// Entries are similar if they have the same message (value of MessageKey) and
+// the same values for the given keys. The first limit similar entries of each
+// interval are written, the following ones are suppressed. Once the interval
+// elapses a summary entry is written in place of the suppressed entries, for
+// example:
+//
+//	msg="suppressed 4312 similar entries" log.suppressed=4312 log.suppressed_msg="connection refused"
+//
+// Summaries are written on the next log call made after the interval elapsed,
+// using any context derived from the same log context, or once the interval
+// elapsed if no such call is made. A limit of 0 disables rate limiting.
+//
+// Rate limiting applies to entries that are written when logged, buffered
+// entries are written as is when flushed as they provide the context of the
+// error that caused the flush. In particular Error flushes the entries
+// buffered in the request context before the error entry is rate limited: a
+// suppressed error entry does not prevent the flush.
+//
+// Usage:
+//
+//	ctx := log.Context(ctx, log.WithRateLimit(10, time.Minute, "user_id"))
+func WithRateLimit(limit int, interval time.Duration, keys ...string) LogOption {
+	return func(o *options) {
+		if limit <= 0 || interval <= 0 {
+			o.rateLimiter = nil
+			return
+		}
+		o.rateLimiter = &rateLimiter{
+			limit:    limit,
+			interval: interval,
+			keys:     keys,
+			buckets:  make(map[string]*rateBucket),
+		}
+	}
+}
+
+// allow returns true if e, logged with l, may be written. It also returns the
+// summary entries of the intervals that elapsed since the last call. The
+// lock of the logger owning the buffer of l must be held.
+func (r *rateLimiter) allow(e *Entry, l *logger) (bool, []*Entry) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	keyvals := l.options.keyvals
+	if len(l.options.redactors) > 0 {
+		keyvals = redact(keyvals, l.options.redactors)
+	}
+	now := timeNow()
+	var summaries []*Entry
+	if !now.Before(r.nextSweep) {
+		summaries = r.sweep(now, keyvals)
+		r.nextSweep = now.Add(r.interval)
+	}
+	key, msg, kvs := r.key(e)
+	b, ok := r.buckets[key]
+	if ok && now.Sub(b.start) >= r.interval {
+		if s := b.summary(now, keyvals); s != nil {
+			summaries = append(summaries, s)
+		}
+		ok = false
+	}
+	if !ok {
+		b = &rateBucket{start: now, msg: msg, keyvals: kvs}
+		r.buckets[key] = b
+	}
+	b.count++
+	if b.count <= r.limit {
+		return true, summaries
+	}
+	b.suppressed++
+	if e.Severity > b.severity {
+		b.severity = e.Severity
+	}
+	r.logger, r.keyvals = l, keyvals
+	if !r.flushing {
+		r.flushing = true
+		afterFunc(b.start.Add(r.interval).Sub(now), r.flush)
+	}
+	return false, summaries
+}
+
+// flush writes the summaries of the intervals that elapsed so that they are
+// written even if nothing is logged afterwards. It is scheduled when an entry
+// is suppressed and reschedules itself while some buckets have suppressed
+// entries.
+func (r *rateLimiter) flush() {
+	r.lock.Lock()
+	now := timeNow()
+	l := r.logger
+	summaries := r.sweep(now, r.keyvals)
+	r.flushing = false
+	var next time.Time
+	for _, b := range r.buckets {
+		if end := b.start.Add(r.interval); b.suppressed > 0 && (next.IsZero() || end.Before(next)) {
+			next = end
+		}
+	}
+	if !next.IsZero() {
+		r.flushing = true
+		afterFunc(next.Sub(now), r.flush)
+	}
+	r.lock.Unlock()
+
+	if len(summaries) == 0 {
+		return
+	}
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for _, s := range summaries {
+		l.writeEntry(s)
+	}
+}
+
+// sweep returns the summaries of the buckets whose interval elapsed and
+// deletes them.
+func (r *rateLimiter) sweep(now time.Time, keyvals []KV) []*Entry {
+	var summaries []*Entry
+	for key, b := range r.buckets {
+		if now.Sub(b.start) < r.interval {
+			continue
+		}
+		if s := b.summary(now, keyvals); s != nil {
+			summaries = append(summaries, s)
+		}
+		delete(r.buckets, key)
+	}
+	return summaries
+}
+
+// key returns the rate limiting key of e as well as the message and key/value
+// pairs it is computed from.
+func (r *rateLimiter) key(e *Entry) (string, any, []KV) {
+	var msg any
+	kvs := make([]KV, 0, len(r.keys))
+	for _, kv := range e.KeyVals {
+		if kv.K == MessageKey && msg == nil {
+			msg = kv.V
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprint(&sb, msg)
+	for _, k := range r.keys {
+		sb.WriteByte(0)
+		for _, kv := range e.KeyVals {
+			if kv.K == k {
+				fmt.Fprint(&sb, kv.V)
+				kvs = append(kvs, kv)
+				break
+			}
+		}
+	}
+	return sb.String(), msg, kvs
+}
+
+// summary returns the entry written in place of the suppressed entries of the
+// bucket or nil if no entry was suppressed.
+func (b *rateBucket) summary(now time.Time, keyvals []KV) *Entry {
+	if b.suppressed == 0 {
+		return nil
+	}
+	kvs := make([]KV, 0, len(keyvals)+len(b.keyvals)+3)
+	kvs = append(kvs, keyvals...)
+	kvs = append(kvs,
+		KV{K: MessageKey, V: fmt.Sprintf("suppressed %d similar entries", b.suppressed)},
+		KV{K: SuppressedEntriesKey, V: b.suppressed},
+	)
+	if b.msg != nil {
+		kvs = append(kvs, KV{K: SuppressedMessageKey, V: b.msg})
+	}
+	kvs = append(kvs, b.keyvals...)
+	return &Entry{Time: now.UTC(), Severity: b.severity, KeyVals: kvs}
+}
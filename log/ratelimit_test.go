@@ -0,0 +1,157 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	restore := timeNow
+	defer func() { timeNow = restore }()
+	now := time.Date(2022, time.February, 22, 17, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	scheduleFlushes(t)
+
+	var buf Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithRateLimit(2, time.Minute, "user"))
+	err := errors.New("connection refused")
+
+	for range 5 {
+		Error(ctx, err, KV{"msg", "db down"}, KV{"user", "alice"})
+	}
+	Error(ctx, err, KV{"msg", "db down"}, KV{"user", "bob"})
+	Error(ctx, err, KV{"msg", "cache down"}, KV{"user", "alice"})
+
+	want := `time=2022-02-22T17:00:00Z level=error err="connection refused" msg="db down" user=alice
+time=2022-02-22T17:00:00Z level=error err="connection refused" msg="db down" user=alice
+time=2022-02-22T17:00:00Z level=error err="connection refused" msg="db down" user=bob
+time=2022-02-22T17:00:00Z level=error err="connection refused" msg="cache down" user=alice
+`
+	assert.Equal(t, want, buf.String())
+
+	// The summary is written on the next log call once the interval elapsed.
+	now = now.Add(time.Minute)
+	Printf(ctx, "hello")
+	want += `time=2022-02-22T17:01:00Z level=error msg="suppressed 3 similar entries" log.suppressed=3 log.suppressed_msg="db down" user=alice
+time=2022-02-22T17:01:00Z level=info msg=hello
+`
+	assert.Equal(t, want, buf.String())
+
+	// Entries are allowed again in the new interval.
+	Error(ctx, err, KV{"msg", "db down"}, KV{"user", "alice"})
+	want += `time=2022-02-22T17:01:00Z level=error err="connection refused" msg="db down" user=alice
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWithRateLimitDerivedContexts(t *testing.T) {
+	restore := timeNow
+	defer func() { timeNow = restore }()
+	now := time.Date(2022, time.February, 22, 17, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	scheduleFlushes(t)
+
+	var buf Buffer
+	root := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithRateLimit(1, time.Second))
+	for _, req := range []string{"a", "b", "c"} {
+		ctx := With(root, KV{"req", req})
+		Printf(ctx, "same")
+	}
+	want := "time=2022-02-22T17:00:00Z level=info req=a msg=same\n"
+	assert.Equal(t, want, buf.String())
+
+	now = now.Add(time.Second)
+	Printf(With(root, KV{"req", "d"}), "other")
+	want += `time=2022-02-22T17:00:01Z level=info msg="suppressed 2 similar entries" log.suppressed=2 log.suppressed_msg=same
+time=2022-02-22T17:00:01Z level=info req=d msg=other
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWithRateLimitFlush(t *testing.T) {
+	restore := timeNow
+	defer func() { timeNow = restore }()
+	now := time.Date(2022, time.February, 22, 17, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	flushes := scheduleFlushes(t)
+
+	var buf Buffer
+	root := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithRateLimit(1, time.Minute))
+	for range 3 {
+		Printf(With(root, KV{"req", "a"}), "same")
+	}
+	now = now.Add(30 * time.Second)
+	Printf(root, "other")
+	Printf(root, "other")
+	require.Len(t, *flushes, 1, "the flush must be scheduled once")
+	assert.Equal(t, time.Minute, (*flushes)[0].delay)
+
+	// The burst stopped: the summary is written once the interval elapsed.
+	now = now.Add(30 * time.Second)
+	(*flushes)[0].fn()
+	want := `time=2022-02-22T17:00:00Z level=info req=a msg=same
+time=2022-02-22T17:00:30Z level=info msg=other
+time=2022-02-22T17:01:00Z level=info msg="suppressed 2 similar entries" log.suppressed=2 log.suppressed_msg=same
+`
+	assert.Equal(t, want, buf.String())
+
+	// The flush is rescheduled for the bucket whose interval did not elapse.
+	require.Len(t, *flushes, 2)
+	assert.Equal(t, 30*time.Second, (*flushes)[1].delay)
+	now = now.Add(30 * time.Second)
+	(*flushes)[1].fn()
+	want += `time=2022-02-22T17:01:30Z level=info msg="suppressed 1 similar entries" log.suppressed=1 log.suppressed_msg=other
+`
+	assert.Equal(t, want, buf.String())
+	assert.Len(t, *flushes, 2, "the flush must not be rescheduled once all summaries are written")
+}
+
+func TestWithRateLimitBuffered(t *testing.T) {
+	var buf Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: testFormat}),
+		WithRateLimit(1, time.Minute))
+	Infof(ctx, "buffered")
+	Infof(ctx, "buffered")
+	require.Len(t, entries(ctx), 2)
+	FlushAndDisableBuffering(ctx)
+	assert.Equal(t, "bufferedbuffered", buf.String(), "buffered entries must not be rate limited")
+}
+
+func TestWithRateLimitDisabled(t *testing.T) {
+	opts := defaultOptions()
+	WithRateLimit(1, time.Minute)(opts)
+	require.NotNil(t, opts.rateLimiter)
+	WithRateLimit(0, time.Minute)(opts)
+	assert.Nil(t, opts.rateLimiter)
+}
+
+// scheduledFlush records a call to afterFunc.
+type scheduledFlush struct {
+	delay time.Duration
+	fn    func()
+}
+
+// scheduleFlushes replaces afterFunc for the duration of the test with a
+// function that records the scheduled calls instead of running them.
+func scheduleFlushes(t *testing.T) *[]scheduledFlush {
+	var flushes []scheduledFlush
+	restore := afterFunc
+	afterFunc = func(d time.Duration, fn func()) *time.Timer {
+		flushes = append(flushes, scheduledFlush{d, fn})
+		return nil
+	}
+	t.Cleanup(func() { afterFunc = restore })
+	return &flushes
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PanicHook is called after a panic has been recovered and logged by the HTTP
+// middleware or the gRPC server interceptors. ctx is the request context, p the
+// value given to panic and stack the stack trace of the goroutine that
+// panicked.
+type PanicHook func(ctx context.Context, p any, stack []byte)
+
+// WithRecovery returns a HTTP middleware option that recovers from panics in
+// the handler. The middleware flushes the log entries buffered for the
+// request, logs the panic value and stack trace at error severity under
+// PanicKey and PanicStackKey, calls hook if not nil and responds with status
+// 500 if the response header has not been written yet.
+//
+// Panics with http.ErrAbortHandler are not recovered.
+//
+// Usage:
+//
+//	handler = log.HTTP(ctx, log.WithRecovery(func(ctx context.Context, p any, stack []byte) {
+//	    sentry.CurrentHub().Recover(p)
+//	}))(handler)
+func WithRecovery(hook PanicHook) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.recovery = true
+		o.panicHook = hook
+	}
+}
+
+// WithCallRecovery returns a gRPC server interceptor option that recovers from
+// panics in the handler. The interceptor flushes the log entries buffered for
+// the call, logs the panic value and stack trace at error severity under
+// PanicKey and PanicStackKey, calls hook if not nil and returns an error with
+// code codes.Internal.
+func WithCallRecovery(hook PanicHook) GRPCLogOption {
+	return func(o *grpcOptions) {
+		o.recovery = true
+		o.panicHook = hook
+	}
+}
+
+// recoverHTTP returns a function that calls h and recovers from panics. The
+// panic is logged with the request context or with the logger of logCtx if the
+// request context does not contain a logger, for example because the request
+// path is filtered out.
+func recoverHTTP(h http.Handler, logCtx context.Context, hook PanicHook) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		rw, ok := w.(*responseCapture)
+		if !ok {
+			rw = &responseCapture{ResponseWriter: w}
+		}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			ctx := req.Context()
+			if _, ok := ctx.Value(ctxLogger).(*logger); !ok {
+				ctx = fork(ctx, logCtx)
+			}
+			handlePanic(ctx, p, hook)
+			if rw.StatusCode == 0 && rw.ContentLength == 0 {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(rw, req)
+	}
+}
+
+// unaryHandler calls handler and recovers from panics if WithCallRecovery is
+// set.
+func (o *grpcOptions) unaryHandler(ctx context.Context, req any, handler grpc.UnaryHandler) (res any, err error) {
+	if o.recovery {
+		defer func() {
+			if p := recover(); p != nil {
+				handlePanic(ctx, p, o.panicHook)
+				res, err = nil, status.Error(codes.Internal, "internal error")
+			}
+		}()
+	}
+	return handler(ctx, req)
+}
+
+// streamHandler calls handler and recovers from panics if WithCallRecovery is
+// set.
+func (o *grpcOptions) streamHandler(srv any, stream grpc.ServerStream, handler grpc.StreamHandler) (err error) {
+	if o.recovery {
+		defer func() {
+			if p := recover(); p != nil {
+				handlePanic(stream.Context(), p, o.panicHook)
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+	}
+	return handler(srv, stream)
+}
+
+// handlePanic logs the recovered panic value p and calls hook.
+func handlePanic(ctx context.Context, p any, hook PanicHook) {
+	stack := debug.Stack()
+	err, ok := p.(error)
+	if !ok {
+		err = fmt.Errorf("panic: %v", p)
+	}
+	Error(ctx, err,
+		KV{K: MessageKey, V: "panic"},
+		KV{K: PanicKey, V: fmt.Sprint(p)},
+		KV{K: PanicStackKey, V: string(stack)})
+	if hook != nil {
+		hook(ctx, p, stack)
+	}
+}
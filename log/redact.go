@@ -0,0 +1,415 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type (
+	// RedactOption is a function that configures a redactor.
+	RedactOption func(*redactor)
+
+	// redactor masks sensitive values before log entries are formatted.
+	redactor struct {
+		keys   []string
+		values []*regexp.Regexp
+		mask   string
+	}
+)
+
+// DefaultRedactMask is the default mask used to replace redacted values.
+const DefaultRedactMask = "[REDACTED]"
+
+// WithRedactor masks sensitive data in log entries before they are buffered
+// or written to any output. The values of keys matching one of the patterns
+// given to RedactKeys are replaced with the mask entirely while the parts of
+// string values matching one of the regular expressions given to RedactValues
+// are replaced with the mask. Slices, maps and structs are redacted
+// recursively, the keys of nested values being their dotted path (e.g.
+// "user.password"). WithRedactor may be used multiple times to configure
+// multiple redactors.
+//
+// Usage:
+//
+//	ctx := log.Context(context.Background(), log.WithRedactor(
+//	    log.RedactKeys("*password*", "http.header.authorization"),
+//	    log.RedactValues(regexp.MustCompile(`\b\d{13,16}\b`)),
+//	))
+func WithRedactor(opts ...RedactOption) LogOption {
+	r := &redactor{mask: DefaultRedactMask}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return func(o *options) {
+		o.redactors = append(o.redactors, r)
+	}
+}
+
+// RedactKeys redacts the values of keys matching the given patterns. Patterns
+// use the syntax of path.Match (e.g. "*password*") and are matched case
+// insensitively.
+func RedactKeys(patterns ...string) RedactOption {
+	return func(r *redactor) {
+		for _, p := range patterns {
+			r.keys = append(r.keys, strings.ToLower(p))
+		}
+	}
+}
+
+// RedactValues redacts the parts of string values matching the given regular
+// expressions. Strings contained in slices, maps, structs, errors and
+// fmt.Stringer values are redacted as well.
+func RedactValues(patterns ...*regexp.Regexp) RedactOption {
+	return func(r *redactor) {
+		r.values = append(r.values, patterns...)
+	}
+}
+
+// RedactMask sets the mask used to replace redacted values, the default is
+// DefaultRedactMask.
+func RedactMask(mask string) RedactOption {
+	return func(r *redactor) {
+		r.mask = mask
+	}
+}
+
+// redact returns keyvals with sensitive values masked. keyvals is copied
+// before being modified so that key/value pairs shared with the log context
+// are left untouched.
+func redact(keyvals kvList, redactors []*redactor) kvList {
+	res, _ := redactKeyVals(keyvals, "", redactors)
+	return res
+}
+
+// redactKeyVals implements redact. Keys of grouped key/value pairs are matched
+// using their dotted path, i.e. prefix followed by the key. It returns whether
+// any value was masked.
+func redactKeyVals(keyvals []KV, prefix string, redactors []*redactor) ([]KV, bool) {
+	copied := false
+	for i, kv := range keyvals {
+		v, changed := kv.V, false
+		if g, ok := v.([]KV); ok {
+			key := prefix + kv.K
+			masked := false
+			for _, r := range redactors {
+				if r.matchKey(key) {
+					v, changed, masked = r.mask, true, true
+					break
+				}
+			}
+			if !masked {
+				v, changed = redactKeyVals(g, key+".", redactors)
+			}
+		} else {
+			for _, r := range redactors {
+				var c bool
+				v, c = r.redact(prefix+kv.K, v)
+				changed = changed || c
+			}
+		}
+		if !changed {
+			continue
+		}
+		if !copied {
+			keyvals = append([]KV(nil), keyvals...)
+			copied = true
+		}
+		keyvals[i] = KV{K: kv.K, V: v}
+	}
+	return keyvals, copied
+}
+
+// maxRedactionDepth is the maximum depth of the values inspected when
+// redacting nested values.
+const maxRedactionDepth = 8
+
+// redact returns the redacted value of the given key/value pair and whether
+// the value was modified.
+func (r *redactor) redact(key string, value any) (any, bool) {
+	return r.redactValue(key, value, 0)
+}
+
+// matchKey returns true if key matches one of the redacted key patterns.
+func (r *redactor) matchKey(key string) bool {
+	if len(r.keys) == 0 {
+		return false
+	}
+	key = strings.ToLower(key)
+	for _, p := range r.keys {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue masks value if key matches one of the redacted key patterns.
+// Otherwise it replaces the parts of the strings contained in value matching
+// the redacted value patterns with the mask and masks the values of nested
+// maps and structs whose dotted path, i.e. key followed by the map key or the
+// field name, matches one of the redacted key patterns. Errors and
+// fmt.Stringer values are redacted using their string representation. It
+// returns the redacted value and whether the value was modified.
+func (r *redactor) redactValue(key string, value any, depth int) (any, bool) {
+	if value == nil {
+		return value, false
+	}
+	if r.matchKey(key) {
+		return r.mask, true
+	}
+	if depth > maxRedactionDepth {
+		return value, false
+	}
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case Field:
+		switch v.kind() {
+		case fieldString:
+			return r.redactString(v.str())
+		case fieldAny:
+			if res, changed := r.redactValue(key, v.any(), depth); changed {
+				return res, true
+			}
+		}
+		return value, false
+	case []string:
+		var res []string
+		for i, s := range v {
+			rs, changed := r.redactString(s)
+			if changed && res == nil {
+				res = slices.Clone(v)
+			}
+			if res != nil {
+				res[i] = rs
+			}
+		}
+		if res == nil {
+			return value, false
+		}
+		return res, true
+	case []any:
+		var res []any
+		for i, e := range v {
+			re, changed := r.redactValue(key, e, depth+1)
+			if changed && res == nil {
+				res = slices.Clone(v)
+			}
+			if res != nil {
+				res[i] = re
+			}
+		}
+		if res == nil {
+			return value, false
+		}
+		return res, true
+	case Fields:
+		if res, changed := r.redactMap(key, v, depth); changed {
+			return Fields(res), true
+		}
+		return value, false
+	case map[string]any:
+		return r.redactMap(key, v, depth)
+	case error:
+		if s, changed := r.redactString(v.Error()); changed {
+			return s, true
+		}
+		return value, false
+	case fmt.Stringer:
+		if s, changed := r.redactString(v.String()); changed {
+			return s, true
+		}
+		return value, false
+	}
+	rv, changed := r.reflectValue(key, reflect.ValueOf(value), depth)
+	if !changed {
+		return value, false
+	}
+	return rv.Interface(), true
+}
+
+// redactString replaces the parts of s matching the redacted value patterns
+// with the mask.
+func (r *redactor) redactString(s string) (string, bool) {
+	res := s
+	for _, re := range r.values {
+		res = re.ReplaceAllLiteralString(res, r.mask)
+	}
+	return res, res != s
+}
+
+// redactMap redacts the values of m, m is copied before being modified.
+func (r *redactor) redactMap(key string, m map[string]any, depth int) (map[string]any, bool) {
+	var res map[string]any
+	for k, e := range m {
+		re, changed := r.redactValue(key+"."+k, e, depth+1)
+		if !changed {
+			continue
+		}
+		if res == nil {
+			res = maps.Clone(m)
+		}
+		res[k] = re
+	}
+	if res == nil {
+		return m, false
+	}
+	return res, true
+}
+
+// reflectValue redacts the strings, slices, arrays, maps and structs contained
+// in v and returns a copy of v and true if anything was redacted, v and false
+// otherwise. Struct fields are matched using their JSON name. Unexported
+// struct fields are not redacted.
+func (r *redactor) reflectValue(key string, v reflect.Value, depth int) (reflect.Value, bool) {
+	if depth > maxRedactionDepth {
+		return v, false
+	}
+	if v.Kind() != reflect.Interface && v.CanInterface() {
+		switch v.Interface().(type) {
+		case error, fmt.Stringer:
+			rv, changed := r.redactValue(key, v.Interface(), depth)
+			if !changed {
+				return v, false
+			}
+			return reflect.ValueOf(rv), true
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		s, changed := r.redactString(v.String())
+		if !changed {
+			return v, false
+		}
+		return reflect.ValueOf(s).Convert(v.Type()), true
+	case reflect.Slice, reflect.Array:
+		var res reflect.Value
+		for i := range v.Len() {
+			elem, changed := r.reflectValue(key, v.Index(i), depth+1)
+			if !changed {
+				continue
+			}
+			if !res.IsValid() {
+				if v.Kind() == reflect.Array {
+					res = reflect.New(v.Type()).Elem()
+				} else {
+					res = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				}
+				reflect.Copy(res, v)
+			}
+			res.Index(i).Set(fit(elem, v.Type().Elem()))
+		}
+		if !res.IsValid() {
+			return v, false
+		}
+		return res, true
+	case reflect.Map:
+		var res reflect.Value
+		iter := v.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			var elem reflect.Value
+			var changed bool
+			if r.matchKey(key + "." + k) {
+				elem, changed = reflect.ValueOf(r.mask), true
+			} else {
+				elem, changed = r.reflectValue(key+"."+k, iter.Value(), depth+1)
+			}
+			if !changed {
+				continue
+			}
+			if !res.IsValid() {
+				res = reflect.MakeMapWithSize(v.Type(), v.Len())
+				other := v.MapRange()
+				for other.Next() {
+					res.SetMapIndex(other.Key(), other.Value())
+				}
+			}
+			res.SetMapIndex(iter.Key(), fit(elem, v.Type().Elem()))
+		}
+		if !res.IsValid() {
+			return v, false
+		}
+		return res, true
+	case reflect.Struct:
+		var res reflect.Value
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fkey := key + "." + jsonName(field)
+			var f reflect.Value
+			var changed bool
+			if r.matchKey(fkey) {
+				f, changed = reflect.ValueOf(r.mask), true
+			} else {
+				f, changed = r.reflectValue(fkey, v.Field(i), depth+1)
+			}
+			if !changed {
+				continue
+			}
+			if !res.IsValid() {
+				res = reflect.New(v.Type()).Elem()
+				res.Set(v)
+			}
+			res.Field(i).Set(fit(f, field.Type))
+		}
+		if !res.IsValid() {
+			return v, false
+		}
+		return res, true
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := r.reflectValue(key, v.Elem(), depth+1)
+		if !changed {
+			return v, false
+		}
+		res := reflect.New(v.Type().Elem())
+		res.Elem().Set(fit(elem, v.Type().Elem()))
+		return res, true
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		return r.reflectValue(key, v.Elem(), depth+1)
+	}
+	return v, false
+}
+
+// errorType is the type of the error interface.
+var errorType = reflect.TypeFor[error]()
+
+// fit returns the redacted value v converted to typ so that it can replace the
+// original value. Redacted strings stored in errors are wrapped with
+// errors.New. Values that cannot be converted are replaced with the zero
+// value of typ so that the original value is not leaked.
+func fit(v reflect.Value, typ reflect.Type) reflect.Value {
+	switch {
+	case v.Type().AssignableTo(typ):
+		return v
+	case v.Kind() == reflect.String && typ.Kind() == reflect.String:
+		return v.Convert(typ)
+	case v.Kind() == reflect.String && typ == errorType:
+		return reflect.ValueOf(errors.New(v.String()))
+	}
+	return reflect.Zero(typ)
+}
+
+// jsonName returns the name of the given struct field in its JSON encoding.
+func jsonName(f reflect.StructField) string {
+	if tag, ok := f.Tag.Lookup("json"); ok {
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
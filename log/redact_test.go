@@ -0,0 +1,153 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	card := regexp.MustCompile(`\b\d{16}\b`)
+	email := regexp.MustCompile(`[\w.]+@[\w.]+`)
+	cases := []struct {
+		name    string
+		opts    []RedactOption
+		keyvals kvList
+		want    kvList
+	}{
+		{"no match", []RedactOption{RedactKeys("password")}, kvList{{"user", "joe"}}, kvList{{"user", "joe"}}},
+		{"key", []RedactOption{RedactKeys("password")}, kvList{{"password", "secret"}}, kvList{{"password", DefaultRedactMask}}},
+		{"key glob", []RedactOption{RedactKeys("*password*")}, kvList{{"db_password_hash", 42}}, kvList{{"db_password_hash", DefaultRedactMask}}},
+		{"key case insensitive", []RedactOption{RedactKeys("http.header.authorization")}, kvList{{"http.header.Authorization", "Bearer x"}}, kvList{{"http.header.Authorization", DefaultRedactMask}}},
+		{"nil value", []RedactOption{RedactKeys("password")}, kvList{{"password", nil}}, kvList{{"password", nil}}},
+		{"value", []RedactOption{RedactValues(card)}, kvList{{"msg", "card 4111111111111111 declined"}}, kvList{{"msg", "card [REDACTED] declined"}}},
+		{"string slice", []RedactOption{RedactValues(email)}, kvList{{"to", []string{"a", "joe@example.com"}}}, kvList{{"to", []string{"a", "[REDACTED]"}}}},
+		{"any slice", []RedactOption{RedactValues(email)}, kvList{{"to", []any{1, "joe@example.com"}}}, kvList{{"to", []any{1, "[REDACTED]"}}}},
+		{"mask", []RedactOption{RedactKeys("token"), RedactMask("***")}, kvList{{"token", "abc"}}, kvList{{"token", "***"}}},
+		{"typed value", []RedactOption{RedactValues(card)}, kvList{{"msg", String("msg", "card 4111111111111111")}}, kvList{{"msg", "card [REDACTED]"}}},
+		{"fields key", []RedactOption{RedactKeys("user.password")}, kvList{{"user", Fields{"name": "joe", "password": "secret"}}}, kvList{{"user", Fields{"name": "joe", "password": DefaultRedactMask}}}},
+		{"nested map", []RedactOption{RedactKeys("*.token"), RedactValues(email)}, kvList{{"req", map[string]any{"auth": map[string]any{"token": "abc"}, "to": []any{"joe@example.com"}}}},
+			kvList{{"req", map[string]any{"auth": map[string]any{"token": DefaultRedactMask}, "to": []any{"[REDACTED]"}}}}},
+		{"nested slice", []RedactOption{RedactValues(email)}, kvList{{"to", []any{[]string{"joe@example.com"}}}}, kvList{{"to", []any{[]string{"[REDACTED]"}}}}},
+		{"struct", []RedactOption{RedactKeys("req.password"), RedactValues(card)}, kvList{{"req", &testRedactStruct{User: "joe", Secret: "pw", Card: "4111111111111111", Tags: map[string]string{"k": "4111111111111111"}}}},
+			kvList{{"req", &testRedactStruct{User: "joe", Secret: DefaultRedactMask, Card: DefaultRedactMask, Tags: map[string]string{"k": DefaultRedactMask}}}}},
+		{"error", []RedactOption{RedactValues(card)}, kvList{{"err", errors.New("card 4111111111111111 declined")}}, kvList{{"err", "card [REDACTED] declined"}}},
+		{"stringer", []RedactOption{RedactValues(email)}, kvList{{"to", testStringer("joe@example.com")}}, kvList{{"to", "[REDACTED]"}}},
+		{"struct error", []RedactOption{RedactValues(card)}, kvList{{"res", testRedactStruct{Err: errors.New("card 4111111111111111")}}}, kvList{{"res", testRedactStruct{Err: errors.New("card [REDACTED]")}}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var o options
+			WithRedactor(c.opts...)(&o)
+			orig := append(kvList(nil), c.keyvals...)
+			got := redact(c.keyvals, o.redactors)
+			assert.Equal(t, c.want, got)
+			assert.Equal(t, orig, c.keyvals, "input must not be modified")
+		})
+	}
+}
+
+type (
+	testRedactStruct struct {
+		User   string
+		Secret string `json:"password"`
+		Card   string
+		Tags   map[string]string
+		Err    error
+	}
+
+	testStringer string
+)
+
+func (s testStringer) String() string { return string(s) }
+
+func TestRedactFormats(t *testing.T) {
+	epoc := epoch
+	epoch = timeNow()
+	defer func() { epoch = epoc }()
+
+	redactor := WithRedactor(
+		RedactKeys("*password*", "http.header.authorization"),
+		RedactValues(regexp.MustCompile(`\b\d{16}\b`)),
+	)
+	keyvals := []Fielder{
+		KV{"user", "joe"},
+		KV{"password", "secret"},
+		KV{"http.header.authorization", "Bearer token"},
+		KV{"msg", "card 4111111111111111"},
+	}
+	cases := []struct {
+		name   string
+		format FormatFunc
+		want   string
+	}{
+		{"text", FormatText, `time=2022-02-22T17:00:00Z level=info user=joe password=[REDACTED] http.header.authorization=[REDACTED] msg="card [REDACTED]"`},
+		{"json", FormatJSON, `{"time":"2022-02-22T17:00:00Z","level":"info","user":"joe","password":"[REDACTED]","http.header.authorization":"[REDACTED]","msg":"card [REDACTED]"}`},
+		{"terminal", FormatTerminal, "\033[34mINFO\033[0m[0000] \033[34muser\033[0m=joe \033[34mpassword\033[0m=[REDACTED] \033[34mhttp.header.authorization\033[0m=[REDACTED] \033[34mmsg\033[0m=card [REDACTED]"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: c.format}), redactor)
+			Print(ctx, keyvals...)
+			assert.Equal(t, c.want+"\n", buf.String())
+		})
+	}
+}
+
+func TestRedactBuffered(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: testFormat}),
+		WithRedactor(RedactKeys("password")))
+	ctx = With(ctx, KV{"password", "secret"})
+	Info(ctx, KV{"msg", "buffered"})
+	if assert.Len(t, entries(ctx), 1) {
+		assert.Equal(t, KV{"password", DefaultRedactMask}, entries(ctx)[0].KeyVals[0])
+	}
+	FlushAndDisableBuffering(ctx)
+	assert.Equal(t, DefaultRedactMask+"buffered", buf.String())
+}
+
+func TestRedactSyntheticEntries(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithRedactor(RedactKeys("password")),
+		WithMaxBufferedEntries(1))
+	ctx = With(ctx, KV{"password", "secret"})
+	Info(ctx, KV{"msg", "one"})
+	Info(ctx, KV{"msg", "two"})
+	FlushAndDisableBuffering(ctx)
+
+	assert.NotContains(t, buf.String(), "secret")
+	assert.Contains(t, buf.String(), `level=warn password=[REDACTED] msg="dropped buffered log entries" log.dropped=1`)
+}
+
+func TestRedactRateLimitSummaries(t *testing.T) {
+	restore := timeNow
+	defer func() { timeNow = restore }()
+	now := time.Date(2022, time.February, 22, 17, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	scheduleFlushes(t)
+
+	var buf bytes.Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatText}),
+		WithRedactor(RedactValues(regexp.MustCompile(`\b\d{16}\b`))),
+		WithRateLimit(1, time.Minute, "card"))
+	FlushAndDisableBuffering(ctx)
+	for range 3 {
+		Print(ctx, KV{"msg", "card 4111111111111111 declined"}, KV{"card", "4111111111111111"})
+	}
+	now = now.Add(time.Minute)
+	Printf(ctx, "hello")
+
+	assert.NotContains(t, buf.String(), "4111111111111111")
+	assert.Contains(t, buf.String(), `msg="suppressed 2 similar entries" log.suppressed=2 log.suppressed_msg="card [REDACTED] declined" card=[REDACTED]`)
+}
@@ -0,0 +1,205 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// DefaultRequestIDHeader is the name of the HTTP header used to
+	// propagate request IDs.
+	DefaultRequestIDHeader = "X-Request-ID"
+	// DefaultRequestIDMetadataKey is the gRPC metadata key used to propagate
+	// request IDs.
+	DefaultRequestIDMetadataKey = "x-request-id"
+
+	// maxRequestIDLength is the maximum length of incoming request IDs.
+	maxRequestIDLength = 128
+)
+
+// RequestID returns the request ID stored in the log context by the HTTP
+// middleware or the gRPC server interceptors, empty if there is none.
+func RequestID(ctx context.Context) string {
+	l, ok := ctx.Value(ctxLogger).(*logger)
+	if !ok {
+		return ""
+	}
+	o := l.owner()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for i := len(l.keyvals) - 1; i >= 0; i-- {
+		if l.keyvals[i].K == RequestIDKey {
+			if id, ok := stringValue(l.keyvals[i].V); ok {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// UUIDv7 returns a new version 7 UUID. It can be used with WithRequestIDFunc
+// and WithCallIDFunc to generate time ordered request IDs.
+func UUIDv7() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// ULID returns a new ULID (https://github.com/ulid/spec): a 48-bit millisecond
+// timestamp followed by 80 random bits encoded as 26 Crockford base32
+// characters. It can be used with WithRequestIDFunc and WithCallIDFunc to
+// generate time ordered request IDs. IDs generated within the same millisecond
+// are not ordered.
+func ULID() string {
+	var id [16]byte
+	ms := uint64(timeNow().UnixMilli()) // nolint: gosec
+	for i := range 6 {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	rand.Read(id[6:]) // nolint: errcheck
+	return encodeULID(id)
+}
+
+// WithRequestIDHeader returns a HTTP middleware option that sets the name of
+// the header used to read incoming request IDs. The default is
+// DefaultRequestIDHeader. An empty name disables the use of incoming request
+// IDs.
+func WithRequestIDHeader(name string) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.requestIDHeader = name
+	}
+}
+
+// WithRequestIDFunc returns a HTTP middleware option that sets the function
+// used to generate request IDs when the request does not have a valid one.
+func WithRequestIDFunc(fn func() string) HTTPLogOption {
+	return func(o *httpLogOptions) {
+		o.requestIDFunc = fn
+	}
+}
+
+// WithClientRequestIDHeader returns a HTTP client logger option that sets the
+// name of the header used to propagate the request ID. The default is
+// DefaultRequestIDHeader. An empty name disables propagation.
+func WithClientRequestIDHeader(name string) HTTPClientLogOption {
+	return func(o *httpClientOptions) {
+		o.requestIDHeader = name
+	}
+}
+
+// WithCallIDMetadataKey returns a GRPC logger option that sets the metadata
+// key used to read incoming request IDs in server interceptors and to
+// propagate them in client interceptors. The default is
+// DefaultRequestIDMetadataKey. An empty key disables both.
+func WithCallIDMetadataKey(key string) GRPCLogOption {
+	return func(o *grpcOptions) {
+		o.callIDKey = key
+	}
+}
+
+// WithCallIDFunc returns a GRPC logger option that sets the function used by
+// server interceptors to generate request IDs when the call does not have a
+// valid one.
+func WithCallIDFunc(fn func() string) GRPCLogOption {
+	return func(o *grpcOptions) {
+		o.callIDFunc = fn
+	}
+}
+
+// requestID returns the ID of req if valid, a new ID otherwise.
+func (o *httpLogOptions) requestID(req *http.Request) string {
+	if o.requestIDHeader != "" {
+		if id := req.Header.Get(o.requestIDHeader); validRequestID(id) {
+			return id
+		}
+	}
+	if o.requestIDFunc != nil {
+		return o.requestIDFunc()
+	}
+	return shortID()
+}
+
+// propagateRequestID returns a copy of req with the request ID header set if
+// the request context contains a request ID and the header is not set yet.
+// It returns req otherwise.
+func (o *httpClientOptions) propagateRequestID(req *http.Request) *http.Request {
+	if o.requestIDHeader == "" || req.Header.Get(o.requestIDHeader) != "" {
+		return req
+	}
+	id := RequestID(req.Context())
+	if id == "" {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(o.requestIDHeader, id)
+	return req
+}
+
+// callID returns the ID found in the incoming metadata of ctx if valid, a new
+// ID otherwise.
+func (o *grpcOptions) callID(ctx context.Context) string {
+	if o.callIDKey != "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get(o.callIDKey); len(ids) > 0 && validRequestID(ids[0]) {
+				return ids[0]
+			}
+		}
+	}
+	if o.callIDFunc != nil {
+		return o.callIDFunc()
+	}
+	return shortID()
+}
+
+// propagateCallID returns a context whose outgoing metadata contains the
+// request ID stored in ctx unless the metadata already contains one.
+func (o *grpcOptions) propagateCallID(ctx context.Context) context.Context {
+	if o.callIDKey == "" || o.disableCallID {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(o.callIDKey)) > 0 {
+		return ctx
+	}
+	id := RequestID(ctx)
+	if id == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, o.callIDKey, id)
+}
+
+// ulidAlphabet is the Crockford base32 alphabet used to encode ULIDs.
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// encodeULID encodes the 128 bits of id in 26 base32 characters, most
+// significant bits first. The first character encodes 3 bits only.
+func encodeULID(id [16]byte) string {
+	var b [26]byte
+	for i := range b {
+		var c byte
+		for j := 5 * i; j < 5*i+5; j++ {
+			c <<= 1
+			if j >= 2 { // 130 bits are encoded, the first 2 are zero
+				c |= id[(j-2)/8] >> (7 - (j-2)%8) & 1
+			}
+		}
+		b[i] = ulidAlphabet[c]
+	}
+	return string(b[:])
+}
+
+// validRequestID returns true if id is not empty, is at most
+// maxRequestIDLength long and only contains printable ASCII characters other
+// than space.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,239 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHTTPRequestID(t *testing.T) {
+	shortID = func() string { return "generated" }
+	defer func() { shortID = randShortID }()
+
+	cases := []struct {
+		name     string
+		opts     []HTTPLogOption
+		header   string
+		value    string
+		expected string
+	}{
+		{"generated", nil, "", "", "generated"},
+		{"incoming", nil, "X-Request-ID", "abc-123", "abc-123"},
+		{"custom header", []HTTPLogOption{WithRequestIDHeader("X-Correlation-ID")}, "X-Correlation-ID", "abc-123", "abc-123"},
+		{"other header ignored", []HTTPLogOption{WithRequestIDHeader("X-Correlation-ID")}, "X-Request-ID", "abc-123", "generated"},
+		{"disabled header", []HTTPLogOption{WithRequestIDHeader("")}, "X-Request-ID", "abc-123", "generated"},
+		{"invalid characters", nil, "X-Request-ID", "abc 123", "generated"},
+		{"too long", nil, "X-Request-ID", strings.Repeat("a", maxRequestIDLength+1), "generated"},
+		{"custom func", []HTTPLogOption{WithRequestIDFunc(func() string { return "custom" })}, "", "", "custom"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := Context(context.Background())
+			var id string
+			handler := HTTP(ctx, append(c.opts, WithDisableRequestLogging())...)(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+				id = RequestID(req.Context())
+			}))
+
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			if c.header != "" {
+				req.Header.Set(c.header, c.value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, c.expected, id)
+		})
+	}
+}
+
+func TestClientRequestID(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     []HTTPClientLogOption
+		id       string
+		preset   string
+		header   string
+		expected string
+	}{
+		{"no request ID", nil, "", "", DefaultRequestIDHeader, ""},
+		{"propagated", nil, "abc", "", DefaultRequestIDHeader, "abc"},
+		{"already set", nil, "abc", "def", DefaultRequestIDHeader, "def"},
+		{"custom header", []HTTPClientLogOption{WithClientRequestIDHeader("X-Correlation-ID")}, "abc", "", "X-Correlation-ID", "abc"},
+		{"disabled", []HTTPClientLogOption{WithClientRequestIDHeader("")}, "abc", "", DefaultRequestIDHeader, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var received string
+			svr := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+				received = req.Header.Get(c.header)
+			}))
+			defer svr.Close()
+			ctx := Context(context.Background(), WithOutputs(Output{Handle: func(*Entry) error { return nil }}))
+			if c.id != "" {
+				ctx = With(ctx, KV{RequestIDKey, c.id})
+			}
+			req, _ := http.NewRequestWithContext(ctx, "GET", svr.URL, nil)
+			if c.preset != "" {
+				req.Header.Set(c.header, c.preset)
+			}
+
+			resp, err := Client(http.DefaultTransport, c.opts...).RoundTrip(req)
+			require.NoError(t, err)
+			resp.Body.Close() // nolint: errcheck
+
+			assert.Equal(t, c.expected, received)
+			if c.preset == "" {
+				assert.Empty(t, req.Header.Get(c.header), "original request must not be modified")
+			}
+		})
+	}
+}
+
+func TestServerInterceptorCallID(t *testing.T) {
+	shortID = func() string { return "generated" }
+	defer func() { shortID = randShortID }()
+
+	cases := []struct {
+		name     string
+		opts     []GRPCLogOption
+		md       metadata.MD
+		expected string
+	}{
+		{"generated", nil, nil, "generated"},
+		{"incoming", nil, metadata.Pairs(DefaultRequestIDMetadataKey, "abc-123"), "abc-123"},
+		{"custom key", []GRPCLogOption{WithCallIDMetadataKey("x-correlation-id")}, metadata.Pairs("x-correlation-id", "abc-123"), "abc-123"},
+		{"disabled key", []GRPCLogOption{WithCallIDMetadataKey("")}, metadata.Pairs(DefaultRequestIDMetadataKey, "abc-123"), "generated"},
+		{"invalid", nil, metadata.Pairs(DefaultRequestIDMetadataKey, "abc\n123"), "generated"},
+		{"custom func", []GRPCLogOption{WithCallIDFunc(func() string { return "custom" })}, nil, "custom"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			logCtx := Context(context.Background())
+			opts := append(c.opts, WithDisableCallLogging())
+			ctx := context.Background()
+			if c.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, c.md)
+			}
+
+			var unaryID string
+			_, err := UnaryServerInterceptor(logCtx, opts...)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Test/Method"},
+				func(ctx context.Context, _ any) (any, error) {
+					unaryID = RequestID(ctx)
+					return nil, nil
+				})
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, unaryID)
+
+			var streamID string
+			err = StreamServerInterceptor(logCtx, opts...)(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test.Test/Stream"},
+				func(_ any, stream grpc.ServerStream) error {
+					streamID = RequestID(stream.Context())
+					return nil
+				})
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, streamID)
+		})
+	}
+}
+
+func TestClientInterceptorCallID(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     []GRPCLogOption
+		id       string
+		md       metadata.MD
+		key      string
+		expected []string
+	}{
+		{"no request ID", nil, "", nil, DefaultRequestIDMetadataKey, nil},
+		{"propagated", nil, "abc", nil, DefaultRequestIDMetadataKey, []string{"abc"}},
+		{"already set", nil, "abc", metadata.Pairs(DefaultRequestIDMetadataKey, "def"), DefaultRequestIDMetadataKey, []string{"def"}},
+		{"custom key", []GRPCLogOption{WithCallIDMetadataKey("x-correlation-id")}, "abc", nil, "x-correlation-id", []string{"abc"}},
+		{"disabled", []GRPCLogOption{WithDisableCallID()}, "abc", nil, DefaultRequestIDMetadataKey, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := Context(context.Background(), WithOutputs(Output{Handle: func(*Entry) error { return nil }}))
+			if c.id != "" {
+				ctx = With(ctx, KV{RequestIDKey, c.id})
+			}
+			if c.md != nil {
+				ctx = metadata.NewOutgoingContext(ctx, c.md)
+			}
+			outgoing := func(ctx context.Context) []string {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				return md.Get(c.key)
+			}
+
+			var unary []string
+			err := UnaryClientInterceptor(c.opts...)(ctx, "/test.Test/Method", nil, nil, nil,
+				func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+					unary = outgoing(ctx)
+					return nil
+				})
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, unary)
+
+			var stream []string
+			_, err = StreamClientInterceptor(c.opts...)(ctx, &grpc.StreamDesc{}, nil, "/test.Test/Stream",
+				func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+					stream = outgoing(ctx)
+					return nil, nil
+				})
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, stream)
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Empty(t, RequestID(context.Background()))
+	ctx := Context(context.Background())
+	assert.Empty(t, RequestID(ctx))
+	ctx = With(ctx, KV{RequestIDKey, "first"})
+	assert.Equal(t, "first", RequestID(ctx))
+	ctx = With(ctx, KV{RequestIDKey, "second"})
+	assert.Equal(t, "second", RequestID(ctx))
+}
+
+func TestUUIDv7(t *testing.T) {
+	id, err := uuid.Parse(UUIDv7())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), id.Version())
+}
+
+func TestULID(t *testing.T) {
+	id := ULID()
+	assert.Len(t, id, 26)
+	assert.True(t, strings.HasPrefix(id, "01FWH608M0"), "the ULID must start with the encoded time, got %s", id)
+	assert.Regexp(t, `^[0-9A-HJKMNP-TV-Z]{26}$`, id)
+	assert.NotEqual(t, id, ULID())
+}
+
+func TestEncodeULID(t *testing.T) {
+	var id [16]byte
+	assert.Equal(t, "00000000000000000000000000", encodeULID(id))
+	for i := range id {
+		id[i] = byte(i)
+	}
+	assert.Equal(t, "00041061050R3GG28A1C60T3GF", encodeULID(id))
+	for i := range id {
+		id[i] = 0xff
+	}
+	assert.Equal(t, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", encodeULID(id))
+}
+
+// testServerStream is a grpc.ServerStream that only implements Context.
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context { return s.ctx }
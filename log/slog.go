@@ -0,0 +1,183 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SlogHandler implements slog.Handler using the clue logger.
+type SlogHandler struct {
+	ctx    context.Context
+	attrs  kvList
+	groups []group
+}
+
+// NewSlogHandler returns a slog.Handler that writes records using the clue
+// logger contained in ctx. Records are buffered in the same way as entries
+// logged with Info and Debug, debug records are only logged when debug
+// logging is enabled and error records flush the buffer.
+//
+// If the context given to the slog Context methods (e.g. InfoContext)
+// contains a clue logger then that logger is used instead of the one in ctx.
+// This makes it possible to use a single slog.Logger across requests while
+// still benefiting from per-request buffering.
+//
+// Usage:
+//
+//	ctx := log.Context(context.Background())
+//	logger := slog.New(log.NewSlogHandler(ctx))
+//	logger.InfoContext(ctx, "hello", "key", "value")
+func NewSlogHandler(ctx context.Context) *SlogHandler {
+	return &SlogHandler{ctx: ctx}
+}
+
+// Enabled returns true if records with the given level are logged. Debug
+// records are only logged if debug logging is enabled in the logger context.
+// Records below the threshold of the LevelVar bound to the logger context if
+// any are not logged.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return enabled(h.logContext(ctx), slogSeverity(level))
+}
+
+// Handle logs the given record.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	logCtx := h.logContext(ctx)
+	fielders := make([]Fielder, 0, 2+r.NumAttrs())
+	if logCtx != h.ctx {
+		// The attributes added via WithAttrs are only part of h.ctx.
+		fielders = append(fielders, h.attrs)
+	}
+	fielders = append(fielders, KV{K: MessageKey, V: r.Message})
+	var kvs kvList
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendSlogAttr(kvs, a)
+		return true
+	})
+	if len(h.groups) > 0 {
+		if kv, ok := nestGroups(h.groups, kvs); ok {
+			fielders = append(fielders, kv)
+		}
+	} else {
+		fielders = append(fielders, kvs)
+	}
+
+	sev := slogSeverity(r.Level)
+	if sev == SeverityError {
+		FlushAndDisableBuffering(logCtx)
+	}
+	log(logCtx, sev, true, fielders)
+	return nil
+}
+
+// WithAttrs returns a new handler whose log context includes the given
+// attributes.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var kvs kvList
+	for _, a := range attrs {
+		kvs = appendSlogAttr(kvs, a)
+	}
+	if len(kvs) == 0 {
+		return h
+	}
+	if len(h.groups) > 0 {
+		return &SlogHandler{
+			ctx:    h.ctx,
+			attrs:  h.attrs,
+			groups: withGrouped(h.groups, []Fielder{kvs}),
+		}
+	}
+	return &SlogHandler{
+		ctx:   With(h.ctx, kvs),
+		attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], kvs...),
+	}
+}
+
+// WithGroup returns a new handler that groups all subsequent attributes under
+// the given group name, see Group.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := make([]group, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+	return &SlogHandler{
+		ctx:    h.ctx,
+		attrs:  h.attrs,
+		groups: append(groups, group{name: name}),
+	}
+}
+
+// logContext returns ctx if it contains a clue logger, h.ctx otherwise.
+func (h *SlogHandler) logContext(ctx context.Context) context.Context {
+	if ctx != nil && ctx.Value(ctxLogger) != nil {
+		return ctx
+	}
+	return h.ctx
+}
+
+// slogSeverity maps the given slog level to a clue log severity.
+func slogSeverity(level slog.Level) Severity {
+	switch {
+	case level < slog.LevelInfo:
+		return SeverityDebug
+	case level < slog.LevelWarn:
+		return SeverityInfo
+	case level < slog.LevelError:
+		return SeverityWarn
+	default:
+		return SeverityError
+	}
+}
+
+// appendSlogAttr appends the key/value pair corresponding to the given
+// attribute to kvs. Groups are converted to []KV values, see Group.
+func appendSlogAttr(kvs kvList, a slog.Attr) kvList {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return kvs
+		}
+		var group kvList
+		for _, ga := range attrs {
+			group = appendSlogAttr(group, ga)
+		}
+		if a.Key == "" {
+			return append(kvs, group...)
+		}
+		if len(group) == 0 {
+			return kvs
+		}
+		return append(kvs, KV{K: a.Key, V: []KV(group)})
+	}
+	if a.Key == "" && v.Kind() == slog.KindAny && v.Any() == nil {
+		return kvs
+	}
+	return append(kvs, KV{K: a.Key, V: slogValue(v)})
+}
+
+// slogValue converts a resolved slog value to a clue log value.
+func slogValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	default:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return v.Any()
+	}
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogHandler(t *testing.T) {
+	restore := timeNow
+	timeNow = func() time.Time { return time.Date(2022, time.January, 9, 20, 29, 45, 0, time.UTC) }
+	defer func() { timeNow = restore }()
+
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatText}))
+	logger := slog.New(NewSlogHandler(ctx))
+
+	logger.Info("hello", "key", "value", "n", 1)
+	assert.Empty(t, buf.String(), "info records must be buffered")
+	require.Len(t, entries(ctx), 1)
+
+	logger.Error("failed", "err", errors.New("boom"))
+	want := "time=2022-01-09T20:29:45Z level=info msg=hello key=value n=1\n" +
+		"time=2022-01-09T20:29:45Z level=error msg=failed err=boom\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestSlogHandlerSeverity(t *testing.T) {
+	cases := []struct {
+		name  string
+		level slog.Level
+		want  Severity
+	}{
+		{"debug", slog.LevelDebug, SeverityDebug},
+		{"below debug", slog.LevelDebug - 4, SeverityDebug},
+		{"info", slog.LevelInfo, SeverityInfo},
+		{"info+2", slog.LevelInfo + 2, SeverityInfo},
+		{"warn", slog.LevelWarn, SeverityWarn},
+		{"error", slog.LevelError, SeverityError},
+		{"above error", slog.LevelError + 4, SeverityError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, slogSeverity(c.level))
+		})
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	ctx := Context(context.Background())
+	h := NewSlogHandler(ctx)
+	assert.False(t, h.Enabled(ctx, slog.LevelDebug))
+	assert.True(t, h.Enabled(ctx, slog.LevelInfo))
+	assert.True(t, h.Enabled(ctx, slog.LevelError))
+
+	debugCtx := Context(context.Background(), WithDebug())
+	assert.True(t, NewSlogHandler(debugCtx).Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, h.Enabled(debugCtx, slog.LevelDebug), "logger in record context must take precedence")
+
+	var level LevelVar
+	level.Set(SeverityWarn)
+	h = NewSlogHandler(Context(context.Background(), WithLevelVar(&level)))
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelWarn))
+}
+
+func TestSlogHandlerDebug(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: testFormat}))
+	logger := slog.New(NewSlogHandler(ctx))
+
+	logger.Debug(ignored)
+	assert.Empty(t, entries(ctx))
+	assert.Empty(t, buf.String())
+
+	ctx = Context(ctx, WithDebug())
+	logger.Debug(printed)
+	assert.Equal(t, printed, buf.String())
+}
+
+func TestSlogHandlerWithAttrsAndGroups(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatText}))
+	FlushAndDisableBuffering(ctx)
+	logger := slog.New(NewSlogHandler(ctx))
+
+	logger.With("svc", "test").WithGroup("req").With("id", 42).Info("msg",
+		slog.Group("user", slog.String("name", "joe")),
+		slog.Group("empty"),
+		slog.Duration("dur", time.Second),
+	)
+
+	want := "time=2022-02-22T17:00:00Z level=info svc=test msg=msg req.id=42 req.user.name=joe req.dur=1s\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestSlogHandlerGroupsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: &buf, Format: FormatJSON}))
+	FlushAndDisableBuffering(ctx)
+	logger := slog.New(NewSlogHandler(ctx))
+
+	logger.WithGroup("req").With("id", 42).WithGroup("db").Info("msg",
+		slog.String("table", "users"),
+		slog.Group("", slog.Int("rows", 3)))
+
+	want := `{"time":"2022-02-22T17:00:00Z","level":"info","msg":"msg","req":{"id":42,"db":{"table":"users","rows":3}}}` + "\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestSlogHandlerRecordContext(t *testing.T) {
+	var rootBuf, reqBuf bytes.Buffer
+	rootCtx := Context(context.Background(), WithOutputs(Output{Writer: &rootBuf, Format: FormatText}))
+	reqCtx := Context(context.Background(), WithOutputs(Output{Writer: &reqBuf, Format: FormatText}))
+	logger := slog.New(NewSlogHandler(rootCtx)).With("svc", "test")
+
+	logger.InfoContext(reqCtx, "request")
+	assert.Empty(t, entries(rootCtx))
+	require.Len(t, entries(reqCtx), 1)
+
+	FlushAndDisableBuffering(reqCtx)
+	assert.Empty(t, rootBuf.String())
+	assert.Equal(t, "time=2022-02-22T17:00:00Z level=info svc=test msg=request\n", reqBuf.String())
+}
@@ -2,10 +2,18 @@ package log
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultSpanEventName is the name of the span events created for log entries
+// that do not have a message.
+const DefaultSpanEventName = "log"
+
 // Span is a log key/value pair generator function that can be used to log trace
 // and span IDs. Usage:
 //
@@ -21,3 +29,118 @@ func Span(ctx context.Context) (kvs []KV) {
 	}
 	return
 }
+
+// WithSpanEvents records each log entry as an event on the active span of the
+// logging context if the span is recording. The event name is the entry
+// message (value of MessageKey) and the other key/value pairs are recorded as
+// event attributes together with the entry severity (log.severity attribute).
+// Entries are recorded when they are logged, whether they are buffered or not.
+//
+// Error also records the error on the span and sets the span status to
+// codes.Error.
+func WithSpanEvents() LogOption {
+	return func(o *options) {
+		o.spanEvents = true
+	}
+}
+
+// addSpanEvent adds e as an event to the span of ctx if it is recording.
+func addSpanEvent(ctx context.Context, e *Entry) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	name := DefaultSpanEventName
+	attrs := make([]attribute.KeyValue, 0, len(e.KeyVals)+1)
+	attrs = append(attrs, attribute.String(SeverityAttributeKey, e.Severity.String()))
+	hasMsg := false
+	for _, kv := range flattenGroups(e.KeyVals) {
+		if kv.K == MessageKey && !hasMsg {
+			name = fmt.Sprint(kv.V)
+			hasMsg = true
+			continue
+		}
+		attrs = append(attrs, spanAttribute(kv.K, kv.V))
+	}
+	span.AddEvent(name, trace.WithTimestamp(e.Time), trace.WithAttributes(attrs...))
+}
+
+// recordSpanError records err on the span of ctx and sets its status to
+// codes.Error if the log context records span events.
+func recordSpanError(ctx context.Context, err error) {
+	l := loggerFrom(ctx)
+	if l == nil {
+		return
+	}
+	o := l.owner()
+	o.lock.Lock()
+	enabled := l.options.spanEvents
+	o.lock.Unlock()
+	if !enabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	if err == nil {
+		span.SetStatus(codes.Error, "")
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// spanAttribute returns the span attribute for the given key/value pair.
+func spanAttribute(k string, v any) attribute.KeyValue {
+	switch v := plainValue(v).(type) {
+	case string:
+		return attribute.String(k, v)
+	case bool:
+		return attribute.Bool(k, v)
+	case int:
+		return attribute.Int(k, v)
+	case int8:
+		return attribute.Int64(k, int64(v))
+	case int16:
+		return attribute.Int64(k, int64(v))
+	case int32:
+		return attribute.Int64(k, int64(v))
+	case int64:
+		return attribute.Int64(k, v)
+	case uint:
+		return attribute.Int64(k, int64(v)) // nolint: gosec
+	case uint8:
+		return attribute.Int64(k, int64(v))
+	case uint16:
+		return attribute.Int64(k, int64(v))
+	case uint32:
+		return attribute.Int64(k, int64(v))
+	case uint64:
+		return attribute.Int64(k, int64(v)) // nolint: gosec
+	case float32:
+		return attribute.Float64(k, float64(v))
+	case float64:
+		return attribute.Float64(k, v)
+	case []string:
+		return attribute.StringSlice(k, v)
+	case []bool:
+		return attribute.BoolSlice(k, v)
+	case []int:
+		return attribute.IntSlice(k, v)
+	case []int64:
+		return attribute.Int64Slice(k, v)
+	case []float64:
+		return attribute.Float64Slice(k, v)
+	case time.Duration:
+		return attribute.String(k, v.String())
+	case time.Time:
+		return attribute.String(k, v.Format(time.RFC3339Nano))
+	case error:
+		return attribute.String(k, v.Error())
+	case fmt.Stringer:
+		return attribute.String(k, v.String())
+	default:
+		return attribute.String(k, fmt.Sprint(v))
+	}
+}
@@ -2,9 +2,15 @@ package log
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -30,3 +36,59 @@ func TestSpan(t *testing.T) {
 		{K: SpanIDKey, V: "0102030405060708"},
 	}, kvs)
 }
+
+func TestWithSpanEvents(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "span")
+	var buf Buffer
+	ctx = Context(ctx, WithOutputs(Output{Writer: &buf, Format: testFormat}), WithSpanEvents())
+
+	Info(ctx, KV{"msg", "hello"}, KV{"n", 1}, KV{"tags", []string{"a", "b"}})
+	Warn(ctx, KV{"k", "v"})
+	err := errors.New("boom")
+	Errorf(ctx, err, "failed")
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	events := spans[0].Events()
+	require.Len(t, events, 4)
+	assert.Equal(t, "hello", events[0].Name)
+	assert.Equal(t, timeNow().UTC(), events[0].Time)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String(SeverityAttributeKey, "info"),
+		attribute.Int("n", 1),
+		attribute.StringSlice("tags", []string{"a", "b"}),
+	}, events[0].Attributes)
+	assert.Equal(t, DefaultSpanEventName, events[1].Name)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String(SeverityAttributeKey, "warn"),
+		attribute.String("k", "v"),
+	}, events[1].Attributes)
+	assert.Equal(t, "failed", events[2].Name)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String(SeverityAttributeKey, "error"),
+		attribute.String(ErrorMessageKey, "boom"),
+	}, events[2].Attributes)
+	assert.Equal(t, "exception", events[3].Name, "Error must record the error")
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "boom", spans[0].Status().Description)
+}
+
+func TestWithSpanEventsDisabled(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "span")
+	var buf Buffer
+	ctx = Context(ctx, WithOutputs(Output{Writer: &buf, Format: testFormat}))
+
+	Infof(ctx, "hello")
+	Errorf(ctx, errors.New("boom"), "failed")
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Empty(t, spans[0].Events())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
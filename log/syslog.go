@@ -0,0 +1,251 @@
+package log
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SyslogWriter is an io.Writer that sends RFC 5424 syslog messages to a
+// syslog server over UDP, TCP or unix sockets. Messages sent over stream
+// oriented connections (tcp and unix) are framed using octet counting as
+// described in RFC 6587. SyslogWriter reconnects when a write fails.
+//
+// SyslogWriter is safe for concurrent use.
+type SyslogWriter struct {
+	network string
+	addr    string
+
+	lock sync.Mutex
+	conn net.Conn
+}
+
+// SyslogFacility is the syslog facility used by FormatSyslog to compute the
+// message priority. The default is 1 (user-level messages).
+var SyslogFacility = 1
+
+// SyslogStructuredDataID is the SD-ID of the structured data element
+// containing the entry key/value pairs.
+var SyslogStructuredDataID = "clue@32473"
+
+// syslogDialTimeout is the timeout used to connect to the syslog server.
+const syslogDialTimeout = 5 * time.Second
+
+// FormatSyslog returns a log formatter that formats entries as RFC 5424 syslog
+// messages:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID - [clue@32473 KEY="VAL" ...] MSG
+//
+// Where PRI is computed from SyslogFacility and the entry severity, TIMESTAMP
+// is the entry time in RFC 3339 format, MSG is the value of the MessageKey key
+// and the other key/value pairs are written as parameters of the structured
+// data element. Each message ends with a newline like the entries written by
+// the other formatters, SyslogWriter removes it before sending the message.
+//
+// Usage:
+//
+//	w, err := log.NewSyslogWriter("udp", "rsyslog:514")
+//	if err != nil {
+//	    return err
+//	}
+//	ctx := log.Context(ctx, log.WithOutputs(log.Output{Writer: w, Format: log.FormatSyslog("svc")}))
+func FormatSyslog(appName string) FormatFunc {
+	hostname, _ := os.Hostname()
+	header := " " + syslogHeaderField(hostname, 255) +
+		" " + syslogHeaderField(appName, 48) +
+		" " + strconv.Itoa(os.Getpid()) +
+		" - "
+	return func(e *Entry) []byte {
+		b := make([]byte, 0, 256)
+		b = append(b, '<')
+		b = strconv.AppendInt(b, int64(SyslogFacility*8+syslogSeverity(e.Severity)), 10)
+		b = append(b, ">1 "...)
+		b = e.Time.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+		b = append(b, header...)
+
+		var msg any
+		hasParams := false
+		for _, kv := range flattenGroups(e.KeyVals) {
+			if kv.K == MessageKey && msg == nil {
+				msg = kv.V
+				continue
+			}
+			if !hasParams {
+				b = append(b, '[')
+				b = append(b, SyslogStructuredDataID...)
+				hasParams = true
+			}
+			b = append(b, ' ')
+			b = appendSyslogParamName(b, kv.K)
+			b = append(b, `="`...)
+			b = appendSyslogParamValue(b, kv.V)
+			b = append(b, '"')
+		}
+		if hasParams {
+			b = append(b, ']')
+		} else {
+			b = append(b, '-')
+		}
+		if msg != nil {
+			b = append(b, ' ')
+			if s, ok := stringValue(msg); ok {
+				b = append(b, s...)
+			} else {
+				b = appendTextValue(b, msg)
+			}
+		}
+		return append(b, '\n')
+	}
+}
+
+// NewSyslogWriter connects to the syslog server at the given address and
+// returns a writer that sends each write as a syslog message. network must be
+// one of "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix" or "unixgram".
+// Trailing newlines are removed from messages before they are sent.
+func NewSyslogWriter(network, addr string) (*SyslogWriter, error) {
+	w := &SyslogWriter{network: network, addr: addr}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write sends p as a single syslog message. If the write fails Write
+// reconnects to the server and retries once.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.conn != nil {
+		if err := w.send(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close() // nolint: errcheck
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if err := w.send(msg); err != nil {
+		w.conn.Close() // nolint: errcheck
+		w.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the syslog server.
+func (w *SyslogWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// connect dials the syslog server. The lock must be held.
+func (w *SyslogWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.addr, syslogDialTimeout)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// send writes msg to the connection using octet counting framing for stream
+// connections. The lock must be held.
+func (w *SyslogWriter) send(msg []byte) error {
+	if !w.isStream() {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+	frame := make([]byte, 0, len(msg)+8)
+	frame = strconv.AppendInt(frame, int64(len(msg)), 10)
+	frame = append(frame, ' ')
+	frame = append(frame, msg...)
+	_, err := w.conn.Write(frame)
+	return err
+}
+
+// isStream returns true if the writer network is stream oriented.
+func (w *SyslogWriter) isStream() bool {
+	switch w.network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+// syslogSeverity maps the given severity to a syslog severity.
+func syslogSeverity(sev Severity) int {
+	switch sev {
+	case SeverityDebug:
+		return 7
+	case SeverityInfo:
+		return 6
+	case SeverityWarn:
+		return 4
+	case SeverityError:
+		return 3
+	default:
+		return 5
+	}
+}
+
+// syslogHeaderField returns s with non printable ASCII characters removed and
+// truncated to max bytes or "-" (the NILVALUE) if empty.
+func syslogHeaderField(s string, max int) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(b) < max; i++ {
+		if s[i] > ' ' && s[i] < 127 {
+			b = append(b, s[i])
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+// appendSyslogParamName appends the structured data parameter name for the
+// given key. Characters not allowed in parameter names are replaced with '_'
+// and the name is truncated to 32 characters.
+func appendSyslogParamName(b []byte, key string) []byte {
+	if key == "" {
+		return append(b, '_')
+	}
+	for i := 0; i < len(key) && i < 32; i++ {
+		c := key[i]
+		if c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// appendSyslogParamValue appends the structured data parameter value for v,
+// escaping '"', '\' and ']'.
+func appendSyslogParamValue(b []byte, v any) []byte {
+	var s string
+	if str, ok := stringValue(v); ok {
+		s = str
+	} else {
+		s = string(appendTextValue(nil, v))
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' || s[i] == ']' {
+			b = append(b, '\\')
+		}
+		b = append(b, s[i])
+	}
+	return b
+}
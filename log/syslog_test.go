@@ -0,0 +1,182 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSyslog(t *testing.T) {
+	hostname, _ := os.Hostname()
+	header := func(pri int) string {
+		return fmt.Sprintf("<%d>1 2022-02-22T17:00:00.000000Z %s svc %d - ", pri, syslogHeaderField(hostname, 255), os.Getpid())
+	}
+	cases := []struct {
+		name    string
+		sev     Severity
+		keyvals []KV
+		want    string
+	}{
+		{"debug", SeverityDebug, []KV{{MessageKey, "hello"}}, header(15) + "- hello"},
+		{"info", SeverityInfo, []KV{{MessageKey, "hello"}}, header(14) + "- hello"},
+		{"warn", SeverityWarn, []KV{{MessageKey, "hello"}}, header(12) + "- hello"},
+		{"error", SeverityError, []KV{{MessageKey, "hello"}}, header(11) + "- hello"},
+		{"structured data", SeverityInfo, []KV{{MessageKey, "hello"}, {"k", "v"}, {"n", 1}},
+			header(14) + `[clue@32473 k="v" n="1"] hello`},
+		{"no message", SeverityInfo, []KV{{"k", "v"}}, header(14) + `[clue@32473 k="v"]`},
+		{"escaped value", SeverityInfo, []KV{{"k", `a"b\c]d`}}, header(14) + `[clue@32473 k="a\"b\\c\]d"]`},
+		{"invalid name", SeverityInfo, []KV{{`a b=c"d]`, "v"}, {strings.Repeat("k", 40), "v"}},
+			header(14) + `[clue@32473 a_b_c_d_="v" ` + strings.Repeat("k", 32) + `="v"]`},
+	}
+	format := FormatSyslog("svc")
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &Entry{Time: timeNow(), Severity: c.sev, KeyVals: c.keyvals}
+			assert.Equal(t, c.want+"\n", string(format(e)))
+		})
+	}
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close() // nolint: errcheck
+	w, err := NewSyslogWriter("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer w.Close() // nolint: errcheck
+
+	ctx := Context(context.Background(), WithOutputs(Output{Writer: w, Format: FormatSyslog("svc")}))
+	Printf(ctx, "hello")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "<14>1 2022-02-22T17:00:00.000000Z "))
+	assert.True(t, strings.HasSuffix(string(buf[:n]), " - - hello"))
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close() // nolint: errcheck
+	msgs := acceptFrames(t, l)
+
+	w, err := NewSyslogWriter("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer w.Close() // nolint: errcheck
+	_, err = w.Write([]byte("first\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("second"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "first", receive(t, msgs))
+	assert.Equal(t, "second", receive(t, msgs))
+}
+
+func TestSyslogWriterUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close() // nolint: errcheck
+	msgs := acceptFrames(t, l)
+
+	w, err := NewSyslogWriter("unix", path)
+	require.NoError(t, err)
+	defer w.Close() // nolint: errcheck
+	_, err = w.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello", receive(t, msgs))
+}
+
+func TestSyslogWriterReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close() // nolint: errcheck
+	w, err := NewSyslogWriter("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer w.Close() // nolint: errcheck
+
+	// Close the first connection server side.
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	msgs := acceptFrames(t, l)
+
+	// Writes to the closed connection eventually fail and cause the writer to
+	// reconnect.
+	require.Eventually(t, func() bool {
+		if _, err := w.Write([]byte("hello")); err != nil {
+			return false
+		}
+		select {
+		case msg := <-msgs:
+			return msg == "hello"
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+}
+
+func TestSyslogWriterDialError(t *testing.T) {
+	_, err := NewSyslogWriter("unix", filepath.Join(t.TempDir(), "missing.sock"))
+	assert.Error(t, err)
+}
+
+// acceptFrames accepts connections on l and sends the octet counted frames
+// read from them on the returned channel.
+func acceptFrames(t *testing.T, l net.Listener) <-chan string {
+	t.Helper()
+	msgs := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close() // nolint: errcheck
+				r := bufio.NewReader(conn)
+				for {
+					size, err := r.ReadString(' ')
+					if err != nil {
+						return
+					}
+					n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+					if err != nil {
+						return
+					}
+					buf := make([]byte, n)
+					if _, err := io.ReadFull(r, buf); err != nil {
+						return
+					}
+					msgs <- string(buf)
+				}
+			}()
+		}
+	}()
+	return msgs
+}
+
+func receive(t *testing.T, msgs <-chan string) string {
+	t.Helper()
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for syslog message")
+		return ""
+	}
+}
@@ -0,0 +1,383 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TruncationPolicy limits the size of log entries. A zero or negative limit
+// disables the corresponding truncation. The keys of the truncated and dropped
+// key/value pairs are listed in a single marker key/value pair (with key
+// TruncatedKeysKey) appended to the entry. Grouped keys are listed using their
+// dotted path.
+type TruncationPolicy struct {
+	// MaxKeys is the maximum number of key/value pairs in an entry and in
+	// each group. Additional key/value pairs are dropped.
+	MaxKeys int
+	// MaxStringBytes is the maximum length in bytes of string values,
+	// including the strings returned by the Error method of errors and the
+	// String method of fmt.Stringer values, and strings contained in slices,
+	// maps and struct fields. Strings are cut on UTF-8 rune boundaries and
+	// suffixed with " ... <clue/log.truncated>".
+	MaxStringBytes int
+	// MaxSliceElements is the maximum number of elements of slice and map
+	// values, including the values contained in other slices, maps and
+	// struct fields. The maps keep the elements with the smallest keys.
+	MaxSliceElements int
+	// MaxEntryBytes is the maximum total size in bytes of the keys and values
+	// of an entry, not including the time and severity. The size of the
+	// values is the size of their text representation. Once the limit is
+	// reached the last string value that does not fit is cut and the
+	// following key/value pairs are dropped.
+	MaxEntryBytes int
+}
+
+// truncator holds the state of the truncation of a single entry.
+type truncator struct {
+	policy TruncationPolicy
+	// keys lists the keys of the truncated and dropped key/value pairs.
+	keys []string
+}
+
+// truncationSuffix is appended to truncated strings.
+const truncationSuffix = " ... <clue/log.truncated>"
+
+// maxTruncationDepth is the maximum depth of the values inspected when
+// truncating slices, maps, structs and pointers.
+const maxTruncationDepth = 8
+
+// WithTruncation sets the policy used to truncate log entries. The default
+// policy limits the number of keys, the length of strings and the number of
+// slice elements to DefaultMaxSize and does not limit the size of entries.
+//
+// Usage:
+//
+//	ctx := log.Context(context.Background(), log.WithTruncation(log.TruncationPolicy{
+//	    MaxKeys:          64,
+//	    MaxStringBytes:   4096,
+//	    MaxSliceElements: 100,
+//	    MaxEntryBytes:    64 * 1024,
+//	}))
+func WithTruncation(policy TruncationPolicy) LogOption {
+	return func(o *options) {
+		o.truncation = policy
+	}
+}
+
+// truncate returns keyvals truncated according to the policy. keyvals may be
+// modified in place, the grouped key/value pairs are copied if truncated.
+func (p TruncationPolicy) truncate(keyvals []KV) []KV {
+	t := truncator{policy: p}
+	keyvals = t.keyvals(keyvals, "", true)
+	if p.MaxEntryBytes > 0 {
+		keyvals = t.limitSize(keyvals)
+	}
+	if len(t.keys) > 0 {
+		keyvals = append(keyvals, KV{K: TruncatedKeysKey, V: t.keys})
+	}
+	return keyvals
+}
+
+// keyvals returns kvs truncated according to the policy. prefix is the
+// dotted path of the enclosing groups. kvs is modified in place if owned is
+// true and copied if needed otherwise.
+func (t *truncator) keyvals(kvs []KV, prefix string, owned bool) []KV {
+	var dropped []KV
+	if max := t.policy.MaxKeys; max > 0 && len(kvs) > max {
+		dropped = kvs[max:]
+		kvs = kvs[:max:max]
+	}
+	for i, kv := range kvs {
+		var (
+			v         any
+			truncated bool
+		)
+		if g, ok := kv.V.([]KV); ok {
+			before := len(t.keys)
+			v = t.keyvals(g, prefix+kv.K+".", false)
+			truncated = len(t.keys) > before
+		} else if v, truncated = t.value(kv.V); truncated {
+			t.keys = append(t.keys, prefix+kv.K)
+		}
+		if !truncated {
+			continue
+		}
+		if !owned {
+			kvs = slices.Clone(kvs)
+			owned = true
+		}
+		kvs[i] = KV{K: kv.K, V: v}
+	}
+	for _, kv := range dropped {
+		t.keys = append(t.keys, prefix+kv.K)
+	}
+	return kvs
+}
+
+// value returns value truncated according to the policy and true if value was
+// truncated, value and false otherwise.
+func (t *truncator) value(value any) (any, bool) {
+	// value is returned as is when not truncated so that it is not boxed
+	// again.
+	switch v := value.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, time.Duration, time.Time:
+		return value, false
+	case string:
+		if s, ok := t.string(v); ok {
+			return s, true
+		}
+		return value, false
+	case []string:
+		if ss, ok := t.strings(v); ok {
+			return ss, true
+		}
+		return value, false
+	case Field:
+		switch v.kind() {
+		case fieldString:
+			if s, ok := t.string(v.str()); ok {
+				return s, true
+			}
+		case fieldAny:
+			if tv, ok := t.value(v.any()); ok {
+				return tv, true
+			}
+		}
+		return value, false
+	case error:
+		if s, ok := t.string(v.Error()); ok {
+			return s, true
+		}
+		return value, false
+	case fmt.Stringer:
+		if s, ok := t.string(v.String()); ok {
+			return s, true
+		}
+		return value, false
+	}
+	rv, truncated := t.reflectValue(reflect.ValueOf(value), 0)
+	if !truncated {
+		return value, false
+	}
+	return rv.Interface(), true
+}
+
+// string returns s cut to MaxStringBytes on a rune boundary and true if s is
+// too long, s and false otherwise.
+func (t *truncator) string(s string) (string, bool) {
+	max := t.policy.MaxStringBytes
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	return cutString(s, max) + truncationSuffix, true
+}
+
+// strings truncates the elements and the strings of ss.
+func (t *truncator) strings(ss []string) ([]string, bool) {
+	truncated := false
+	if max := t.policy.MaxSliceElements; max > 0 && len(ss) > max {
+		ss = ss[:max]
+		truncated = true
+	}
+	var res []string
+	for i, s := range ss {
+		cut, ok := t.string(s)
+		if !ok {
+			continue
+		}
+		if res == nil {
+			res = slices.Clone(ss)
+		}
+		res[i] = cut
+	}
+	if res != nil {
+		return res, true
+	}
+	return ss, truncated
+}
+
+// reflectValue truncates the strings, slices, arrays and maps contained in v
+// and returns a copy of v and true if anything was truncated, v and false
+// otherwise. Unexported struct fields are not truncated.
+func (t *truncator) reflectValue(v reflect.Value, depth int) (reflect.Value, bool) {
+	if depth > maxTruncationDepth {
+		return v, false
+	}
+	switch v.Kind() {
+	case reflect.String:
+		s, truncated := t.string(v.String())
+		if !truncated {
+			return v, false
+		}
+		return reflect.ValueOf(s).Convert(v.Type()), true
+	case reflect.Slice, reflect.Array:
+		return t.reflectSlice(v, depth)
+	case reflect.Map:
+		return t.reflectMap(v, depth)
+	case reflect.Struct:
+		var res reflect.Value
+		for i := range v.NumField() {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			f, truncated := t.reflectValue(v.Field(i), depth+1)
+			if !truncated {
+				continue
+			}
+			if !res.IsValid() {
+				res = reflect.New(v.Type()).Elem()
+				res.Set(v)
+			}
+			res.Field(i).Set(f)
+		}
+		if !res.IsValid() {
+			return v, false
+		}
+		return res, true
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, truncated := t.reflectValue(v.Elem(), depth+1)
+		if !truncated {
+			return v, false
+		}
+		res := reflect.New(v.Type().Elem())
+		res.Elem().Set(elem)
+		return res, true
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		return t.reflectValue(v.Elem(), depth+1)
+	}
+	return v, false
+}
+
+// reflectSlice truncates the slice or array v. The number of elements of
+// arrays is not truncated so that the type of the value is preserved.
+func (t *truncator) reflectSlice(v reflect.Value, depth int) (reflect.Value, bool) {
+	n, truncated := v.Len(), false
+	if max := t.policy.MaxSliceElements; max > 0 && n > max && v.Kind() == reflect.Slice {
+		n, truncated = max, true
+	}
+	var res reflect.Value
+	for i := range n {
+		elem, ok := t.reflectValue(v.Index(i), depth+1)
+		if !ok {
+			continue
+		}
+		if !res.IsValid() {
+			if v.Kind() == reflect.Array {
+				res = reflect.New(v.Type()).Elem()
+			} else {
+				res = reflect.MakeSlice(v.Type(), n, n)
+			}
+			reflect.Copy(res, v)
+		}
+		res.Index(i).Set(elem)
+	}
+	switch {
+	case res.IsValid():
+		return res, true
+	case truncated:
+		return v.Slice3(0, n, n), true
+	}
+	return v, false
+}
+
+// reflectMap truncates the map v.
+func (t *truncator) reflectMap(v reflect.Value, depth int) (reflect.Value, bool) {
+	keys := v.MapKeys()
+	truncated := false
+	if max := t.policy.MaxSliceElements; max > 0 && len(keys) > max {
+		type namedKey struct {
+			name string
+			key  reflect.Value
+		}
+		named := make([]namedKey, len(keys))
+		for i, k := range keys {
+			named[i] = namedKey{fmt.Sprint(k.Interface()), k}
+		}
+		slices.SortFunc(named, func(a, b namedKey) int { return strings.Compare(a.name, b.name) })
+		keys = keys[:max]
+		for i := range keys {
+			keys[i] = named[i].key
+		}
+		truncated = true
+	}
+	values := make([]reflect.Value, len(keys))
+	for i, k := range keys {
+		elem, ok := t.reflectValue(v.MapIndex(k), depth+1)
+		values[i] = elem
+		truncated = truncated || ok
+	}
+	if !truncated {
+		return v, false
+	}
+	res := reflect.MakeMapWithSize(v.Type(), len(keys))
+	for i, k := range keys {
+		res.SetMapIndex(k, values[i])
+	}
+	return res, true
+}
+
+// limitSize drops the key/value pairs of kvs that do not fit in
+// MaxEntryBytes. The last string value that does not fit is cut if there is
+// room left for part of it.
+func (t *truncator) limitSize(kvs []KV) []KV {
+	budget := t.policy.MaxEntryBytes
+	for i, kv := range kvs {
+		size := len(kv.K) + valueSize(kv.V)
+		if size <= budget {
+			budget -= size
+			continue
+		}
+		if s, ok := stringValue(kv.V); ok {
+			if n := budget - len(kv.K) - len(truncationSuffix); n > 0 {
+				kvs[i] = KV{K: kv.K, V: cutString(s, n) + truncationSuffix}
+				t.keys = append(t.keys, kv.K)
+				i++
+			}
+		}
+		for _, kv := range kvs[i:] {
+			t.keys = append(t.keys, kv.K)
+		}
+		return kvs[:i]
+	}
+	return kvs
+}
+
+// valueSize returns the size in bytes of the text representation of v.
+func valueSize(v any) int {
+	switch v := v.(type) {
+	case string:
+		return len(v)
+	case Field:
+		if v.kind() == fieldString {
+			return int(v.num)
+		}
+	case []KV:
+		size := 0
+		for _, kv := range v {
+			size += len(kv.K) + valueSize(kv.V)
+		}
+		return size
+	}
+	var scratch [64]byte
+	return len(appendPlainValue(scratch[:0], v))
+}
+
+// cutString returns the longest prefix of s that is at most n bytes long and
+// does not split a UTF-8 rune. len(s) must be greater than n.
+func cutString(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
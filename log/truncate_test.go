@@ -0,0 +1,82 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	truncUser struct {
+		Name  string
+		Tags  []string
+		Inner *truncUser
+		note  string
+	}
+
+	truncName string
+
+	truncStringer struct{}
+)
+
+func (truncStringer) String() string { return "stringer" }
+
+func TestTruncationPolicy(t *testing.T) {
+	policy := TruncationPolicy{MaxKeys: 3, MaxStringBytes: 4, MaxSliceElements: 2}
+	user := &truncUser{Name: "joe", Tags: []string{"a", "b", "c"}, Inner: &truncUser{Name: "jackson"}, note: "unexported"}
+	cases := []struct {
+		name     string
+		policy   TruncationPolicy
+		keyvals  []KV
+		want     []KV
+		wantKeys []string
+	}{
+		{"none", policy, []KV{{"s", "abcd"}, {"i", 12345}, {"b", []byte("ab")}}, []KV{{"s", "abcd"}, {"i", 12345}, {"b", []byte("ab")}}, nil},
+		{"zero policy", TruncationPolicy{}, []KV{{"s", "abcdef"}}, []KV{{"s", "abcdef"}}, nil},
+		{"string", policy, []KV{{"s", "abcdef"}}, []KV{{"s", "abcd" + truncationSuffix}}, []string{"s"}},
+		{"rune boundary", policy, []KV{{"s", "aé€b"}}, []KV{{"s", "aé" + truncationSuffix}}, []string{"s"}},
+		{"keys", policy, []KV{{"a", 1}, {"b", "abcdef"}, {"c", 3}, {"d", 4}, {"e", 5}}, []KV{{"a", 1}, {"b", "abcd" + truncationSuffix}, {"c", 3}}, []string{"b", "d", "e"}},
+		{"strings", policy, []KV{{"ss", []string{"abcdef", "b", "c"}}}, []KV{{"ss", []string{"abcd" + truncationSuffix, "b"}}}, []string{"ss"}},
+		{"slice", policy, []KV{{"is", []int{1, 2, 3}}}, []KV{{"is", []int{1, 2}}}, []string{"is"}},
+		{"any slice", policy, []KV{{"as", []any{"abcdef", 1}}}, []KV{{"as", []any{"abcd" + truncationSuffix, 1}}}, []string{"as"}},
+		{"array", policy, []KV{{"arr", [3]string{"abcdef", "b", "c"}}}, []KV{{"arr", [3]string{"abcd" + truncationSuffix, "b", "c"}}}, []string{"arr"}},
+		{"map", policy, []KV{{"m", map[string]string{"c": "3", "a": "abcdef", "b": "2"}}}, []KV{{"m", map[string]string{"a": "abcd" + truncationSuffix, "b": "2"}}}, []string{"m"}},
+		{"struct", policy, []KV{{"u", *user}}, []KV{{"u", truncUser{Name: "joe", Tags: []string{"a", "b"}, Inner: &truncUser{Name: "jack" + truncationSuffix}, note: "unexported"}}}, []string{"u"}},
+		{"pointer", policy, []KV{{"u", &truncUser{Name: "jackson"}}}, []KV{{"u", &truncUser{Name: "jack" + truncationSuffix}}}, []string{"u"}},
+		{"named string", policy, []KV{{"n", truncName("abcdef")}}, []KV{{"n", truncName("abcd" + truncationSuffix)}}, []string{"n"}},
+		{"error", policy, []KV{{"err", errors.New("abcdef")}}, []KV{{"err", "abcd" + truncationSuffix}}, []string{"err"}},
+		{"stringer", policy, []KV{{"s", truncStringer{}}}, []KV{{"s", "stri" + truncationSuffix}}, []string{"s"}},
+		{"typed string", policy, []KV{{"s", String("s", "abcdef")}}, []KV{{"s", "abcd" + truncationSuffix}}, []string{"s"}},
+		{"entry size typed", TruncationPolicy{MaxEntryBytes: 40}, []KV{{"msg", String("msg", strings.Repeat("x", 50))}}, []KV{{"msg", strings.Repeat("x", 12) + truncationSuffix}}, []string{"msg"}},
+		{"group", policy, []KV{Group("g", KV{"k", "abcdef"}, Group("h", KV{"a", 1}, KV{"b", 2}, KV{"c", 3}, KV{"d", 4}))}, []KV{Group("g", KV{"k", "abcd" + truncationSuffix}, Group("h", KV{"a", 1}, KV{"b", 2}, KV{"c", 3}))}, []string{"g.k", "g.h.d"}},
+		{"entry size", TruncationPolicy{MaxEntryBytes: 40}, []KV{{"a", 1}, {"msg", strings.Repeat("x", 50)}, {"b", 2}}, []KV{{"a", 1}, {"msg", strings.Repeat("x", 10) + truncationSuffix}}, []string{"msg", "b"}},
+		{"entry size no room", TruncationPolicy{MaxEntryBytes: 10}, []KV{{"a", "abc"}, {"msg", strings.Repeat("x", 50)}, {"b", 2}}, []KV{{"a", "abc"}}, []string{"msg", "b"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.policy.truncate(append([]KV(nil), c.keyvals...))
+			if c.wantKeys != nil {
+				c.want = append(c.want, KV{TruncatedKeysKey, c.wantKeys})
+			}
+			assert.Equal(t, c.want, got)
+		})
+	}
+	assert.Equal(t, "jackson", user.Inner.Name, "truncation must not modify the logged values")
+	assert.Len(t, user.Tags, 3)
+}
+
+func TestWithTruncation(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := Context(context.Background(),
+		WithOutputs(Output{Writer: &buf, Format: FormatJSON}),
+		WithTruncation(TruncationPolicy{MaxStringBytes: 5, MaxSliceElements: 1}))
+	FlushAndDisableBuffering(ctx)
+
+	Print(ctx, KV{"msg", "héllo world"}, KV{"ids", []int{1, 2}}, KV{"n", 42})
+
+	assert.Equal(t, `{"time":"2022-02-22T17:00:00Z","level":"info","msg":"héll ... <clue/log.truncated>","ids":[1],"n":42,"log.truncated":["msg","ids"]}`+"\n", buf.String())
+}